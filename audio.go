@@ -0,0 +1,270 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the api's upload limit for audio files
+const MaxAudioUploadBytes = 25 << 20
+
+type TranscriptionRequest struct {
+	Model                  string   // defaults to whisper-1
+	Language               string   // iso-639-1 input language; transcription only
+	Prompt                 string   // optional context or style guidance
+	ResponseFormat         string   // json (default), text, srt, vtt, or verbose_json
+	Temperature            *float64 // sampling temperature
+	TimestampGranularities []string // word and/or segment; requires verbose_json
+	MaxFileBytes           int      // split larger files, defaults to MaxAudioUploadBytes
+}
+
+// a typed transcription or translation; for text, srt, and vtt formats only Text
+// is set, holding the whole response body.
+type Transcription struct {
+	Task     string                 `json:"task,omitempty"`
+	Language string                 `json:"language,omitempty"`
+	Duration float64                `json:"duration,omitempty"`
+	Text     string                 `json:"text"`
+	Segments []TranscriptionSegment `json:"segments,omitempty"`
+	Words    []TranscriptionWord    `json:"words,omitempty"`
+	Usage    *TranscriptionUsage    `json:"usage,omitempty"`
+}
+
+// what a transcription was billed for: seconds of audio (type duration) for
+// whisper-1, or tokens (type tokens) for the gpt-4o transcription models
+type TranscriptionUsage struct {
+	Type              string                     `json:"type"`
+	Seconds           float64                    `json:"seconds,omitempty"`
+	InputTokens       int                        `json:"input_tokens,omitempty"`
+	InputTokenDetails *TranscriptionTokenDetails `json:"input_token_details,omitempty"`
+	OutputTokens      int                        `json:"output_tokens,omitempty"`
+	TotalTokens       int                        `json:"total_tokens,omitempty"`
+}
+
+type TranscriptionTokenDetails struct {
+	TextTokens  int `json:"text_tokens"`
+	AudioTokens int `json:"audio_tokens"`
+}
+
+// adds another chunk's usage, which is billed the same way
+func (u *TranscriptionUsage) Add(x TranscriptionUsage) {
+	if len(u.Type) == 0 {
+		u.Type = x.Type
+	}
+	u.Seconds += x.Seconds
+	u.InputTokens += x.InputTokens
+	u.OutputTokens += x.OutputTokens
+	u.TotalTokens += x.TotalTokens
+	if d := x.InputTokenDetails; d != nil {
+		if u.InputTokenDetails == nil {
+			u.InputTokenDetails = new(TranscriptionTokenDetails)
+		}
+		u.InputTokenDetails.TextTokens += d.TextTokens
+		u.InputTokenDetails.AudioTokens += d.AudioTokens
+	}
+}
+
+type TranscriptionSegment struct {
+	ID               int     `json:"id"`
+	Seek             int     `json:"seek"`
+	Start            float64 `json:"start"`
+	End              float64 `json:"end"`
+	Text             string  `json:"text"`
+	Tokens           []int   `json:"tokens,omitempty"`
+	Temperature      float64 `json:"temperature"`
+	AvgLogprob       float64 `json:"avg_logprob"`
+	CompressionRatio float64 `json:"compression_ratio"`
+	NoSpeechProb     float64 `json:"no_speech_prob"`
+}
+
+type TranscriptionWord struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+func (t Transcription) String() string {
+	return toString(t)
+}
+
+// transcribes an audio file in its own language
+func (c *Client) Transcribe(ctx context.Context, filename string, r TranscriptionRequest) (*Transcription, error) {
+	return c.audioFile(ctx, "audio/transcriptions", filename, r)
+}
+
+// transcribes an audio file into english
+func (c *Client) Translate(ctx context.Context, filename string, r TranscriptionRequest) (*Transcription, error) {
+	r.Language = ""
+	return c.audioFile(ctx, "audio/translations", filename, r)
+}
+
+// handles files over the upload limit by splitting wav files and stitching the results
+func (c *Client) audioFile(ctx context.Context, endpoint, filename string, r TranscriptionRequest) (*Transcription, error) {
+	if r.MaxFileBytes == 0 {
+		r.MaxFileBytes = MaxAudioUploadBytes
+	}
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(filename)
+	if len(buf) <= r.MaxFileBytes {
+		return c.audio(ctx, endpoint, name, buf, r)
+	}
+	w, err := ParseWAV(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%q is over %d bytes, and only wav files can be split: %w", filename, r.MaxFileBytes, err)
+	}
+	chunks, err := w.Split(r.MaxFileBytes)
+	if err != nil {
+		return nil, err
+	}
+	var out Transcription
+	prompt := r.Prompt
+	for i, chunk := range chunks {
+		cr := r
+		cr.Prompt = prompt
+		t, err := c.audio(ctx, endpoint, fmt.Sprintf("%s.%d.wav", strings.TrimSuffix(name, filepath.Ext(name)), i), chunk.Bytes(), cr)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		if err := out.append(t, chunk.Offset, r.ResponseFormat); err != nil {
+			return nil, fmt.Errorf("can't stitch chunk %d: %w", i+1, err)
+		}
+		// whisper continues more naturally given the preceding text
+		prompt = lastWords(r.Prompt+" "+t.plainText(r.ResponseFormat), 100)
+	}
+	return &out, nil
+}
+
+func (c *Client) audio(ctx context.Context, endpoint, name string, buf []byte, r TranscriptionRequest) (*Transcription, error) {
+	fields := make(url.Values)
+	model := r.Model
+	if len(model) == 0 {
+		model = "whisper-1"
+	}
+	fields.Set("model", model)
+	if len(r.Language) > 0 {
+		fields.Set("language", r.Language)
+	}
+	if len(r.Prompt) > 0 {
+		fields.Set("prompt", r.Prompt)
+	}
+	if len(r.ResponseFormat) > 0 {
+		fields.Set("response_format", r.ResponseFormat)
+	}
+	if r.Temperature != nil {
+		fields.Set("temperature", strconv.FormatFloat(*r.Temperature, 'f', -1, 64))
+	}
+	for _, g := range r.TimestampGranularities {
+		fields.Add("timestamp_granularities[]", g)
+	}
+	resp, err := c.DoMultipartRequest(ctx, "POST", endpoint, fields, []FormFile{{
+		Field:    "file",
+		Filename: name,
+		Reader:   bytes.NewReader(buf),
+	}})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch r.ResponseFormat {
+	case "text", "srt", "vtt":
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &Transcription{Text: string(body)}, nil
+	default:
+		var t Transcription
+		if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+			return nil, err
+		}
+		return &t, nil
+	}
+}
+
+// appends a chunk's transcription, shifting its timestamps by offset
+func (t *Transcription) append(x *Transcription, offset time.Duration, format string) error {
+	secs := offset.Seconds()
+	switch format {
+	case "srt", "vtt":
+		prev, err := ParseSubtitles(t.Text)
+		if err != nil {
+			return err
+		}
+		next, err := ParseSubtitles(x.Text)
+		if err != nil {
+			return err
+		}
+		t.Text, err = append(prev, next.Shift(offset)...).Format(format)
+		return err
+	case "text":
+		t.Text = joinNonEmpty(" ", strings.TrimSpace(t.Text), strings.TrimSpace(x.Text))
+		return nil
+	}
+	if len(t.Task) == 0 {
+		t.Task = x.Task
+		t.Language = x.Language
+	}
+	t.Duration = max(t.Duration, secs+x.Duration)
+	t.Text = joinNonEmpty(" ", strings.TrimSpace(t.Text), strings.TrimSpace(x.Text))
+	for _, s := range x.Segments {
+		s.ID = len(t.Segments)
+		s.Start += secs
+		s.End += secs
+		t.Segments = append(t.Segments, s)
+	}
+	for _, w := range x.Words {
+		w.Start += secs
+		w.End += secs
+		t.Words = append(t.Words, w)
+	}
+	if u := x.Usage; u != nil {
+		if t.Usage == nil {
+			t.Usage = new(TranscriptionUsage)
+		}
+		t.Usage.Add(*u)
+	}
+	return nil
+}
+
+// the spoken text, without any subtitle markup
+func (t Transcription) plainText(format string) string {
+	switch format {
+	case "srt", "vtt":
+		s, err := ParseSubtitles(t.Text)
+		if err != nil {
+			return ""
+		}
+		return s.Text()
+	}
+	return t.Text
+}
+
+func lastWords(s string, n int) string {
+	words := strings.Fields(s)
+	if len(words) > n {
+		words = words[len(words)-n:]
+	}
+	return strings.Join(words, " ")
+}
+
+func joinNonEmpty(sep string, list ...string) string {
+	var out []string
+	for _, s := range list {
+		if len(s) > 0 {
+			out = append(out, s)
+		}
+	}
+	return strings.Join(out, sep)
+}
@@ -0,0 +1,331 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type Batch struct {
+	ID               string             `json:"id"`
+	Object           string             `json:"object"`
+	Endpoint         string             `json:"endpoint"`
+	Errors           *List[BatchError]  `json:"errors,omitempty"`
+	InputFileID      string             `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"` // validating, failed, in_progress, finalizing, completed, expired, cancelling, or cancelled
+	OutputFileID     string             `json:"output_file_id,omitempty"`
+	ErrorFileID      string             `json:"error_file_id,omitempty"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     int64              `json:"in_progress_at,omitempty"`
+	ExpiresAt        int64              `json:"expires_at,omitempty"`
+	FinalizingAt     int64              `json:"finalizing_at,omitempty"`
+	CompletedAt      int64              `json:"completed_at,omitempty"`
+	FailedAt         int64              `json:"failed_at,omitempty"`
+	ExpiredAt        int64              `json:"expired_at,omitempty"`
+	CancellingAt     int64              `json:"cancelling_at,omitempty"`
+	CancelledAt      int64              `json:"cancelled_at,omitempty"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata,omitempty"`
+}
+
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (b Batch) String() string {
+	return toString(b)
+}
+
+// whether the batch has reached a terminal status
+func (b Batch) Done() bool {
+	switch b.Status {
+	case "completed", "failed", "expired", "cancelled":
+		return true
+	}
+	return false
+}
+
+// one line of a batch input file
+type BatchRequestLine struct {
+	CustomID string `json:"custom_id"`
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	Body     any    `json:"body"`
+}
+
+// one line of a batch output or error file
+type BatchResponseLine struct {
+	ID       string `json:"id"`
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		RequestID  string          `json:"request_id"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *BatchError `json:"error"`
+}
+
+// the outcome of one request in a chat batch
+type BatchResult struct {
+	CustomID string
+	Response *ChatCompletionResponse
+	Error    *BatchError
+}
+
+type BatchOptions struct {
+	CustomIDs    []string          // one per request, defaulting to "request-<index>"
+	Metadata     map[string]string // attached to the batch
+	StateFile    string            // if set, the batch id is saved here, and an existing one is resumed
+	PollInterval time.Duration     // initial poll interval, defaults to 10s and backs off to 5m
+	Progress     func(*Batch)      // called after each poll
+}
+
+func (o BatchOptions) customID(i int) string {
+	if i < len(o.CustomIDs) {
+		return o.CustomIDs[i]
+	}
+	return fmt.Sprintf("request-%d", i)
+}
+
+// the batch input file for chat requests; streaming is disabled
+func ChatBatchJSONL(reqs []ChatRequest, o BatchOptions) ([]byte, error) {
+	if len(o.CustomIDs) > 0 && len(o.CustomIDs) != len(reqs) {
+		return nil, fmt.Errorf("%d custom ids for %d requests", len(o.CustomIDs), len(reqs))
+	}
+	w := new(bytes.Buffer)
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	seen := make(map[string]bool)
+	for i, r := range reqs {
+		id := o.customID(i)
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate custom id: %q", id)
+		}
+		seen[id] = true
+		r.Stream = false
+		if err := e.Encode(BatchRequestLine{
+			CustomID: id,
+			Method:   "POST",
+			URL:      "/v1/chat/completions",
+			Body:     r,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return w.Bytes(), nil
+}
+
+type createBatchRequest struct {
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// creates a batch from an uploaded input file, for an endpoint like /v1/chat/completions
+func (c *Client) CreateBatch(ctx context.Context, inputFileID, endpoint string, metadata map[string]string) (*Batch, error) {
+	var out Batch
+	if err := c.PostContext(ctx, "batches", createBatchRequest{
+		InputFileID:      inputFileID,
+		Endpoint:         endpoint,
+		CompletionWindow: "24h",
+		Metadata:         metadata,
+	}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) RetrieveBatch(ctx context.Context, id string) (*Batch, error) {
+	var out Batch
+	if err := c.GetContext(ctx, "batches/"+id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) CancelBatch(ctx context.Context, id string) (*Batch, error) {
+	var out Batch
+	if err := c.PostContext(ctx, "batches/"+id+"/cancel", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) ListBatches(ctx context.Context, o ListOptions) (*List[Batch], error) {
+	return listPage[Batch](ctx, c, "batches", o.values())
+}
+
+// builds the input file, uploads it, and creates the batch
+func (c *Client) SubmitChatBatch(ctx context.Context, reqs []ChatRequest, o BatchOptions) (*Batch, error) {
+	buf, err := ChatBatchJSONL(reqs, o)
+	if err != nil {
+		return nil, err
+	}
+	f, err := c.UploadFileReader(ctx, "batch.jsonl", bytes.NewReader(buf), PurposeBatch)
+	if err != nil {
+		return nil, fmt.Errorf("can't upload batch input: %w", err)
+	}
+	b, err := c.CreateBatch(ctx, f.ID, "/v1/chat/completions", o.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.StateFile) > 0 {
+		if err := os.WriteFile(o.StateFile, []byte(b.ID+"\n"), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// polls until the batch is done, backing off exponentially
+func (c *Client) WaitBatch(ctx context.Context, id string, o BatchOptions) (*Batch, error) {
+	interval := o.PollInterval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	for {
+		b, err := c.RetrieveBatch(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if o.Progress != nil {
+			o.Progress(b)
+		}
+		if b.Done() {
+			return b, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval = min(interval*3/2, 5*time.Minute)
+	}
+}
+
+// downloads a finished chat batch's output and error files, returning results
+// in input order; n is the number of requests submitted.
+func (c *Client) ChatBatchResults(ctx context.Context, b *Batch, n int, o BatchOptions) ([]BatchResult, error) {
+	index := make(map[string]int)
+	out := make([]BatchResult, n)
+	for i := range out {
+		id := o.customID(i)
+		index[id] = i
+		out[i].CustomID = id
+	}
+	for _, fileID := range []string{b.OutputFileID, b.ErrorFileID} {
+		if len(fileID) == 0 {
+			continue
+		}
+		buf := new(bytes.Buffer)
+		if err := c.DownloadFile(ctx, fileID, buf); err != nil {
+			return nil, err
+		}
+		s := bufio.NewScanner(buf)
+		s.Buffer(nil, 64<<20)
+		for s.Scan() {
+			if len(bytes.TrimSpace(s.Bytes())) == 0 {
+				continue
+			}
+			var line BatchResponseLine
+			if err := json.Unmarshal(s.Bytes(), &line); err != nil {
+				return nil, fmt.Errorf("bad batch output line: %w", err)
+			}
+			i, ok := index[line.CustomID]
+			if !ok {
+				return nil, fmt.Errorf("unknown custom id: %q", line.CustomID)
+			}
+			r := &out[i]
+			switch {
+			case line.Error != nil:
+				r.Error = line.Error
+			case line.Response == nil:
+				r.Error = &BatchError{Code: "no_response", Message: "batch line has neither response nor error"}
+			case line.Response.StatusCode != 200:
+				var body struct {
+					Error *BatchError `json:"error"`
+				}
+				json.Unmarshal(line.Response.Body, &body)
+				r.Error = body.Error
+				if r.Error == nil {
+					r.Error = &BatchError{Code: fmt.Sprint(line.Response.StatusCode), Message: string(line.Response.Body)}
+				}
+			default:
+				var resp ChatCompletionResponse
+				if err := json.Unmarshal(line.Response.Body, &resp); err != nil {
+					return nil, fmt.Errorf("can't decode response for %q: %w", line.CustomID, err)
+				}
+				r.Response = &resp
+			}
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+	}
+	for i := range out {
+		if out[i].Response == nil && out[i].Error == nil {
+			out[i].Error = &BatchError{Code: "missing", Message: fmt.Sprintf("no result (batch %s)", b.Status)}
+		}
+	}
+	return out, nil
+}
+
+// the whole flow: submits the requests (or resumes the batch in the state file),
+// waits for completion, and collects the results in input order
+func (c *Client) RunChatBatch(ctx context.Context, reqs []ChatRequest, o BatchOptions) ([]BatchResult, error) {
+	var id string
+	if len(o.StateFile) > 0 {
+		buf, err := os.ReadFile(o.StateFile)
+		if err == nil {
+			id = strings.TrimSpace(string(buf))
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if len(id) == 0 {
+		b, err := c.SubmitChatBatch(ctx, reqs, o)
+		if err != nil {
+			return nil, err
+		}
+		id = b.ID
+	}
+	return c.ResumeChatBatch(ctx, id, len(reqs), o)
+}
+
+// waits for an already submitted batch of n requests and collects its results
+func (c *Client) ResumeChatBatch(ctx context.Context, id string, n int, o BatchOptions) ([]BatchResult, error) {
+	b, err := c.WaitBatch(ctx, id, o)
+	if err != nil {
+		return nil, err
+	}
+	if b.Status == "failed" {
+		var list []string
+		if b.Errors != nil {
+			for _, e := range b.Errors.Data {
+				list = append(list, e.Error())
+			}
+		}
+		return nil, fmt.Errorf("batch %s failed: %s", id, strings.Join(list, "; "))
+	}
+	return c.ChatBatchResults(ctx, b, n, o)
+}
@@ -0,0 +1,327 @@
+package openai
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// model features
+const (
+	FeatureTools      = "tools"
+	FeatureVision     = "vision"
+	FeatureJSONSchema = "json_schema"
+	FeatureReasoning  = "reasoning"
+	FeatureAudio      = "audio"
+)
+
+// what's known about a model: limits, features, and prices in dollars per
+// million tokens, from the catalog, joined with the live model list
+type ModelInfo struct {
+	ID               string   `json:"id"`
+	Aliases          []string `json:"aliases,omitempty"`
+	ContextWindow    int      `json:"context_window,omitempty"`
+	MaxOutputTokens  int      `json:"max_output_tokens,omitempty"`
+	Features         []string `json:"features,omitempty"`
+	InputPrice       float64  `json:"input_price,omitempty"`
+	CachedInputPrice float64  `json:"cached_input_price,omitempty"`
+	OutputPrice      float64  `json:"output_price,omitempty"`
+	Created          int      `json:"created,omitempty"`   // from /v1/models
+	OwnedBy          string   `json:"owned_by,omitempty"`  // from /v1/models
+	Available        bool     `json:"available,omitempty"` // listed by /v1/models
+	Cataloged        bool     `json:"-"`                   // described by the catalog, not just listed
+}
+
+func (m ModelInfo) Supports(feature string) bool {
+	return slices.Contains(m.Features, feature)
+}
+
+// dollars for the given token counts; cached tokens are a subset of input tokens
+func (m ModelInfo) Cost(input, cached, output int) float64 {
+	price := m.CachedInputPrice
+	if price == 0 {
+		price = m.InputPrice
+	}
+	return (float64(input-cached)*m.InputPrice + float64(cached)*price + float64(output)*m.OutputPrice) / 1e6
+}
+
+//go:embed models.json
+var catalogJSON []byte
+
+// models by id, with aliases
+type Catalog struct {
+	models map[string]*ModelInfo
+	alias  map[string]string
+}
+
+// a fresh copy of the embedded catalog
+func DefaultCatalog() *Catalog {
+	c, err := ParseCatalog(catalogJSON)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// parses a json array of models
+func ParseCatalog(buf []byte) (*Catalog, error) {
+	c := &Catalog{
+		models: make(map[string]*ModelInfo),
+		alias:  make(map[string]string),
+	}
+	if err := c.Merge(buf); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// overrides or extends the catalog with a json array of models; entries replace
+// those with the same id
+func (c *Catalog) Merge(buf []byte) error {
+	var list []ModelInfo
+	if err := json.Unmarshal(buf, &list); err != nil {
+		return fmt.Errorf("bad model catalog: %w", err)
+	}
+	for _, m := range list {
+		c.Set(m)
+	}
+	return nil
+}
+
+func (c *Catalog) MergeFile(filename string) error {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return c.Merge(buf)
+}
+
+// adds or replaces a model, keeping any live details already joined
+func (c *Catalog) Set(m ModelInfo) {
+	if len(m.ID) == 0 {
+		return
+	}
+	if old, ok := c.models[m.ID]; ok {
+		m.Created, m.OwnedBy, m.Available = old.Created, old.OwnedBy, old.Available
+	}
+	m.Cataloged = true
+	c.models[m.ID] = &m
+	for _, a := range m.Aliases {
+		c.alias[a] = m.ID
+	}
+}
+
+// finds a model by id or alias, falling back to the base of a fine-tune
+// (ft:gpt-4o-mini-2024-07-18:org::id). snapshots priced differently from their
+// base model are listed on their own, and unlisted ones are unknown.
+func (c *Catalog) Lookup(id string) (ModelInfo, bool) {
+	find := func(id string) *ModelInfo {
+		if m, ok := c.models[id]; ok && m.Cataloged {
+			return m
+		}
+		if base, ok := c.alias[id]; ok {
+			return c.models[base]
+		}
+		return nil
+	}
+	m := find(id)
+	if m == nil && strings.HasPrefix(id, "ft:") {
+		base, _, _ := strings.Cut(strings.TrimPrefix(id, "ft:"), ":")
+		m = find(base)
+	}
+	if m == nil {
+		return ModelInfo{}, false
+	}
+	out := *m
+	if id != m.ID {
+		if live, ok := c.models[id]; ok {
+			out.Created, out.OwnedBy, out.Available = live.Created, live.OwnedBy, live.Available
+		} else {
+			out.Created, out.OwnedBy, out.Available = 0, "", false
+		}
+		out.ID = id
+	}
+	return out, true
+}
+
+// marks the listed models as available, adding bare entries for any the catalog
+// doesn't describe by id; Lookup describes those by their alias or base model
+func (c *Catalog) Join(live []Model) {
+	for _, x := range live {
+		m, ok := c.models[x.ID]
+		if !ok {
+			m = &ModelInfo{ID: x.ID}
+			c.models[x.ID] = m
+		}
+		m.Created = x.Created
+		m.OwnedBy = x.OwnedBy
+		m.Available = true
+	}
+}
+
+// the models, sorted by id
+func (c *Catalog) Models() []ModelInfo {
+	var out []ModelInfo
+	for _, m := range c.models {
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// the embedded catalog joined with the models this key can use
+func (c *Client) LoadCatalog(ctx context.Context) (*Catalog, error) {
+	var list ModelResponse
+	if err := c.GetContext(ctx, "models", &list); err != nil {
+		return nil, err
+	}
+	out := DefaultCatalog()
+	out.Join(list.Data)
+	return out, nil
+}
+
+// what a model must offer; zero values don't constrain
+type ModelRequirements struct {
+	Features       []string
+	ContextWindow  int     // at least this many tokens
+	OutputTokens   int     // at least this many output tokens
+	MaxInputPrice  float64 // per million tokens
+	MaxOutputPrice float64 // per million tokens
+	Available      bool    // listed by /v1/models
+}
+
+// returned when a model can't satisfy requirements
+type ModelError struct {
+	Model    string
+	Problems []string
+}
+
+func (e *ModelError) Error() string {
+	return fmt.Sprintf("model %s: %s", e.Model, strings.Join(e.Problems, "; "))
+}
+
+// why the model doesn't meet the requirements, if it doesn't
+func (m ModelInfo) Check(r ModelRequirements) error {
+	var problems []string
+	for _, f := range r.Features {
+		if !m.Supports(f) {
+			problems = append(problems, "no "+f+" support")
+		}
+	}
+	if r.ContextWindow > 0 && m.ContextWindow > 0 && r.ContextWindow > m.ContextWindow {
+		problems = append(problems, fmt.Sprintf("needs %d tokens of context, has %d", r.ContextWindow, m.ContextWindow))
+	}
+	if r.OutputTokens > 0 && m.MaxOutputTokens > 0 && r.OutputTokens > m.MaxOutputTokens {
+		problems = append(problems, fmt.Sprintf("needs %d output tokens, allows %d", r.OutputTokens, m.MaxOutputTokens))
+	}
+	if r.MaxInputPrice > 0 && m.InputPrice > r.MaxInputPrice {
+		problems = append(problems, fmt.Sprintf("input price $%g over $%g", m.InputPrice, r.MaxInputPrice))
+	}
+	if r.MaxOutputPrice > 0 && m.OutputPrice > r.MaxOutputPrice {
+		problems = append(problems, fmt.Sprintf("output price $%g over $%g", m.OutputPrice, r.MaxOutputPrice))
+	}
+	if r.Available && !m.Available {
+		problems = append(problems, "not available")
+	}
+	if len(problems) > 0 {
+		return &ModelError{Model: m.ID, Problems: problems}
+	}
+	return nil
+}
+
+// cataloged models meeting the requirements, cheapest first
+func (c *Catalog) Filter(r ModelRequirements) []ModelInfo {
+	var out []ModelInfo
+	for _, m := range c.Models() {
+		if m.Cataloged && m.Check(r) == nil {
+			out = append(out, m)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].InputPrice+out[i].OutputPrice < out[j].InputPrice+out[j].OutputPrice
+	})
+	return out
+}
+
+// the cheapest model meeting the requirements
+func (c *Catalog) Pick(r ModelRequirements) (ModelInfo, error) {
+	list := c.Filter(r)
+	if len(list) == 0 {
+		return ModelInfo{}, fmt.Errorf("no model meets the requirements")
+	}
+	return list[0], nil
+}
+
+// checks a model against the requirements; models the catalog doesn't describe
+// pass, since nothing is known about them
+func (c *Catalog) Validate(model string, r ModelRequirements) error {
+	m, ok := c.Lookup(model)
+	if !ok {
+		return nil
+	}
+	return m.Check(r)
+}
+
+// what the chat request needs of its model
+func ChatRequirements(r ChatRequest) ModelRequirements {
+	var out ModelRequirements
+	add := func(f string) {
+		if !slices.Contains(out.Features, f) {
+			out.Features = append(out.Features, f)
+		}
+	}
+	if len(r.Tools) > 0 {
+		add(FeatureTools)
+	}
+	if f := r.ResponseFormat; f != nil && f.Type == "json_schema" {
+		add(FeatureJSONSchema)
+	}
+	for _, m := range r.Messages {
+		for _, p := range m.Parts {
+			switch p.Type {
+			case "image_url":
+				add(FeatureVision)
+			case "input_audio":
+				add(FeatureAudio)
+			}
+		}
+	}
+	p := r.parameters()
+	out.OutputTokens = max(p.MaxTokens, p.MaxCompletionTokens)
+	out.ContextWindow = estimateChatTokens(r.Model, r.Messages, r.Tools) + out.OutputTokens
+	return out
+}
+
+// refuses a chat request its model can't satisfy: missing features, too long for
+// the context window, or sampling parameters reasoning models reject
+func (c *Catalog) CheckChat(r ChatRequest) error {
+	m, ok := c.Lookup(r.Model)
+	if !ok {
+		return nil
+	}
+	err := m.Check(ChatRequirements(r))
+	if m.Supports(FeatureReasoning) {
+		var problems []string
+		if err, ok := err.(*ModelError); ok {
+			problems = err.Problems
+		}
+		p := r.parameters()
+		if t := p.Temperature; t != nil && *t != 1 {
+			problems = append(problems, "reasoning models only take the default temperature")
+		}
+		if p.MaxTokens > 0 {
+			problems = append(problems, "reasoning models take max_completion_tokens, not max_tokens")
+		}
+		if len(problems) > 0 {
+			return &ModelError{Model: r.Model, Problems: problems}
+		}
+	}
+	return err
+}
@@ -0,0 +1,221 @@
+package openai
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCatalogLookup(t *testing.T) {
+	c := DefaultCatalog()
+	base := func(id string) ModelInfo {
+		m, ok := c.models[id]
+		if !ok {
+			t.Fatalf("no %s in the catalog", id)
+		}
+		return *m
+	}
+	for _, tt := range []struct {
+		id   string
+		base string // empty if unknown
+	}{
+		{"gpt-4o", "gpt-4o"},
+		{"gpt-4o-2024-08-06", "gpt-4o"},
+		{"gpt-4o-2024-11-20", "gpt-4o"},
+		{"gpt-4o-2024-05-13", "gpt-4o-2024-05-13"}, // priced on its own
+		{"chatgpt-4o-latest", "chatgpt-4o-latest"},
+		{"gpt-4o-mini-2024-07-18", "gpt-4o-mini"},
+		{"ft:gpt-4o-mini-2024-07-18:acme::abc123", "gpt-4o-mini"},
+		{"ft:gpt-4o-mini-2024-07-18:acme:custom-suffix:abc123", "gpt-4o-mini"},
+		{"ft:gpt-4o-2024-05-13:acme::abc123", "gpt-4o-2024-05-13"},
+		{"ft:gpt-4.1", "gpt-4.1"},
+		{"gpt-4o-2099-01-01", ""}, // unlisted snapshots aren't guessed at
+		{"gpt-4o-mini-tts", ""},
+		{"ft:davinci-002:acme::abc123", ""},
+		{"ft:", ""},
+		{"", ""},
+	} {
+		m, ok := c.Lookup(tt.id)
+		if ok != (len(tt.base) > 0) {
+			t.Errorf("%q: found %v", tt.id, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		want := base(tt.base)
+		want.ID = tt.id
+		if !slices.Equal(m.Features, want.Features) || m.InputPrice != want.InputPrice || m.OutputPrice != want.OutputPrice || m.ContextWindow != want.ContextWindow || m.ID != want.ID {
+			t.Errorf("%q: got %+v, want %+v", tt.id, m, want)
+		}
+	}
+}
+
+func TestCatalogJoin(t *testing.T) {
+	c := DefaultCatalog()
+	c.Join([]Model{
+		{ID: "gpt-4o-2024-08-06", Created: 5, OwnedBy: "system"},
+		{ID: "gpt-4o-mini", Created: 6},
+		{ID: "my-model", Created: 7},
+	})
+	for _, tt := range []struct {
+		id        string
+		found     bool
+		available bool
+		created   int
+	}{
+		{"gpt-4o-2024-08-06", true, true, 5}, // listed under its alias
+		{"gpt-4o", true, false, 0},           // but not under its own id
+		{"gpt-4o-2024-11-20", true, false, 0},
+		{"gpt-4o-mini", true, true, 6},
+		{"ft:gpt-4o-mini-2024-07-18:acme::abc123", true, false, 0},
+		{"my-model", false, false, 0}, // listed, but nothing's known about it
+	} {
+		m, ok := c.Lookup(tt.id)
+		if ok != tt.found || m.Available != tt.available || m.Created != tt.created {
+			t.Errorf("%s: got %v, available %v, created %d", tt.id, ok, m.Available, m.Created)
+		}
+	}
+	if m, _ := c.Lookup("gpt-4o-2024-08-06"); m.OwnedBy != "system" || !m.Supports(FeatureJSONSchema) {
+		t.Errorf("got %+v", m)
+	}
+	if !slices.ContainsFunc(c.Models(), func(m ModelInfo) bool { return m.ID == "my-model" && !m.Cataloged }) {
+		t.Error("my-model isn't listed")
+	}
+
+	// merging keeps what was joined
+	if err := c.Merge([]byte(`[{"id":"gpt-4o-mini","input_price":1,"output_price":2}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := c.Lookup("gpt-4o-mini"); m.InputPrice != 1 || !m.Available || m.Created != 6 {
+		t.Errorf("after merging: %+v", m)
+	}
+	if err := c.Merge([]byte(`{}`)); err == nil {
+		t.Error("merged an object")
+	}
+}
+
+func TestModelCost(t *testing.T) {
+	m := ModelInfo{InputPrice: 2, CachedInputPrice: 0.5, OutputPrice: 8}
+	if got, want := m.Cost(1000, 400, 100), (600*2+400*0.5+100*8)/1e6; math.Abs(got-want) > 1e-15 {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	m.CachedInputPrice = 0 // no discount
+	if got, want := m.Cost(1000, 400, 100), (1000*2+100*8)/1e6; math.Abs(got-want) > 1e-15 {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCheckChat(t *testing.T) {
+	c := DefaultCatalog()
+	user := []Message{{Role: "user", Content: "hi"}}
+	image := []Message{{Role: "user", Parts: []Content{ImageContent("https://example.com/a.png", DetailLow)}}}
+	tools := []Tool{{Type: "function", Function: &Function{Name: "f"}}}
+	for _, tt := range []struct {
+		name string
+		r    ChatRequest
+		want []string // the problems
+	}{
+		{"plain", ChatRequest{Model: "o3-mini", Messages: user}, nil},
+		{"default temperature", ChatRequest{Model: "o3-mini", Messages: user, ChatParameters: ChatParameters{Temperature: Ptr(1.0)}}, nil},
+		{"max completion tokens", ChatRequest{Model: "o4-mini", Messages: user, ChatParameters: ChatParameters{MaxCompletionTokens: 1000}}, nil},
+		{
+			"temperature",
+			ChatRequest{Model: "o3-mini", Messages: user, ChatParameters: ChatParameters{Temperature: Ptr(0.0)}},
+			[]string{"reasoning models only take the default temperature"},
+		},
+		{
+			"request temperature",
+			ChatRequest{Model: "o1-2024-12-17", Messages: user, Temperature: 0.7},
+			[]string{"reasoning models only take the default temperature"},
+		},
+		{
+			"max tokens",
+			ChatRequest{Model: "o3", Messages: user, MaxTokens: 100},
+			[]string{"reasoning models take max_completion_tokens, not max_tokens"},
+		},
+		{
+			"everything",
+			ChatRequest{Model: "o3-mini", Messages: image, ChatParameters: ChatParameters{MaxTokens: 200000, Temperature: Ptr(0.5)}},
+			[]string{
+				"no vision support",
+				"needs 200092 tokens of context, has 200000",
+				"needs 200000 output tokens, allows 100000",
+				"reasoning models only take the default temperature",
+				"reasoning models take max_completion_tokens, not max_tokens",
+			},
+		},
+		{"not reasoning", ChatRequest{Model: "gpt-4o", Messages: user, MaxTokens: 100, Temperature: 0.2}, nil},
+		{"tools", ChatRequest{Model: "chatgpt-4o-latest", Messages: user, Tools: tools}, []string{"no tools support"}},
+		{"schema", ChatRequest{Model: "gpt-4-turbo", Messages: user, ResponseFormat: &ResponseFormat{Type: "json_schema"}}, []string{"no json_schema support"}},
+		{"unknown", ChatRequest{Model: "my-model", Messages: image, Tools: tools, Temperature: 0.1}, nil},
+	} {
+		var got []string
+		switch err := c.CheckChat(tt.r).(type) {
+		case nil:
+		case *ModelError:
+			if err.Model != tt.r.Model {
+				t.Errorf("%s: error for %s", tt.name, err.Model)
+			}
+			got = err.Problems
+		default:
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChatRequirements(t *testing.T) {
+	r := ChatRequest{
+		Model: "gpt-4o",
+		Messages: []Message{
+			{Role: "user", Parts: []Content{TextContent("what's this?"), ImageContent("https://example.com/a.png", DetailLow), AudioContent([]byte("RIFF"), "wav")}},
+		},
+		Tools:          []Tool{{Type: "function", Function: &Function{Name: "f"}}},
+		ResponseFormat: &ResponseFormat{Type: "json_schema"},
+		MaxTokens:      100,
+		ChatParameters: ChatParameters{MaxCompletionTokens: 300},
+	}
+	got := ChatRequirements(r)
+	if want := []string{FeatureTools, FeatureJSONSchema, FeatureVision, FeatureAudio}; !slices.Equal(got.Features, want) {
+		t.Errorf("features %q, want %q", got.Features, want)
+	}
+	n, err := CountChatTokens(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.OutputTokens != 300 || got.ContextWindow != n+300 {
+		t.Errorf("got %d output tokens, %d context; want 300, %d", got.OutputTokens, got.ContextWindow, n+300)
+	}
+}
+
+func TestCatalogPick(t *testing.T) {
+	c := DefaultCatalog()
+	list := c.Filter(ModelRequirements{Features: []string{FeatureReasoning, FeatureVision}})
+	if len(list) == 0 {
+		t.Fatal("no reasoning models with vision")
+	}
+	for i, m := range list {
+		if !m.Supports(FeatureReasoning) || !m.Supports(FeatureVision) {
+			t.Errorf("%s lacks a feature", m.ID)
+		}
+		if i > 0 && m.InputPrice+m.OutputPrice < list[i-1].InputPrice+list[i-1].OutputPrice {
+			t.Errorf("%s is cheaper than %s", m.ID, list[i-1].ID)
+		}
+	}
+	if m, err := c.Pick(ModelRequirements{Features: []string{FeatureReasoning, FeatureVision}}); err != nil || m.ID != list[0].ID {
+		t.Errorf("picked %s, %v", m.ID, err)
+	}
+	if _, err := c.Pick(ModelRequirements{Available: true}); err == nil {
+		t.Error("picked a model that isn't available")
+	}
+	if err := c.Validate("gpt-3.5-turbo", ModelRequirements{ContextWindow: 20000}); err == nil || !strings.Contains(err.Error(), "16385") {
+		t.Errorf("got %v", err)
+	}
+	if err := c.Validate("my-model", ModelRequirements{ContextWindow: 1 << 30}); err != nil {
+		t.Errorf("unknown model: %v", err)
+	}
+}
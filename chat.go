@@ -2,6 +2,8 @@ package openai
 
 import (
 	"bufio"
+	"cmp"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +12,24 @@ import (
 )
 
 func Chat(c *Client, prompts ...string) error {
+	o := StandardChatOptions(prompts...)
+	return ChatAndPrint(c, &o)
+}
+
+// runs the chat, then prints its transcript, as Chat does
+func ChatAndPrint(c *Client, o *ChatOptions) error {
+	if err := ChatWithOptions(c, o); err != nil {
+		return err
+	}
+	fmt.Println()
+	for _, m := range o.Messages {
+		fmt.Printf("%s: %s\n", m.Role, m.Text())
+	}
+	return nil
+}
+
+// the interactive assistant used by Chat, with standard funcs and system prompt
+func StandardChatOptions(prompts ...string) ChatOptions {
 	var messages []Message
 	messages = append(messages, Message{
 		Role: "system",
@@ -26,20 +46,15 @@ func Chat(c *Client, prompts ...string) error {
 			Content: p,
 		})
 	}
-	o := ChatOptions{
+	return ChatOptions{
 		Functions:      StandardFuncs(),
 		Messages:       messages,
 		Model:          "gpt-4-turbo-preview",
 		ResponseFormat: "text",
+		ChatParameters: ChatParameters{
+			Temperature: Ptr(0.7),
+		},
 	}
-	if err := ChatWithOptions(c, &o); err != nil {
-		return err
-	}
-	fmt.Println()
-	for _, m := range o.Messages {
-		fmt.Printf("%s: %s\n", m.Role, m.Content)
-	}
-	return nil
 }
 
 func StandardFuncs() (out []FunctionI) {
@@ -69,11 +84,23 @@ type ChatOptions struct {
 	Messages       []Message
 	Model          string
 	OneRound       bool
-	ResponseFormat string // text or json_object
+	ResponseFormat string            // text or json_object
+	ChatParameters                   // passed through on every request
+	Speech         *SpeechOptions    // if set, assistant replies are read aloud into files
+	Moderation     *ModerationPolicy // if set, messages are moderated per the policy
+	Responses      *ResponsesOptions // if set, rounds run on the responses api rather than chat completions
+	Catalog        *Catalog          // if set, requests the model can't satisfy are refused
+	History        HistoryStrategy   // if set, compacts what's sent before each request, leaving Messages whole
+	Session        *Session          // if set, updated and saved after every round
+	Tree           *Conversation     // if set, messages are also recorded in the tree, and input lines starting with / are tree commands
 }
 
 func ChatWithOptions(c *Client, o *ChatOptions) error {
 
+	if err := o.singleChoice(); err != nil {
+		return err
+	}
+
 	var reader *bufio.Reader
 
 	if !o.OneRound {
@@ -82,6 +109,27 @@ func ChatWithOptions(c *Client, o *ChatOptions) error {
 
 	var toolCall bool
 
+	// user turns, each with the tool calls it leads to, for cost labels
+	var turn int
+
+	ctx := context.Background()
+
+	// index of the first message not yet moderated
+	var moderated int
+
+	// assistant output is held back from streaming until it's been moderated
+	hold := o.Moderation != nil && o.Moderation.Assistant
+
+	// when chaining responses, the last response and the messages it covers
+	var previous string
+	var sent int
+
+	// what's sent to the model: the compacted history standing in for the
+	// first covered messages, then the rest. o.Messages stays the full
+	// transcript, for sessions and the tree.
+	var window []Message
+	var covered int
+
 	funcs := make(map[string]FunctionI)
 	add := func(f FunctionI) {
 		fd := functionDefinition(f)
@@ -99,6 +147,16 @@ func ChatWithOptions(c *Client, o *ChatOptions) error {
 		add(f)
 	}
 
+	if t := o.Tree; t != nil {
+		if len(t.Nodes) == 0 {
+			for _, m := range o.Messages {
+				t.Append(m)
+			}
+		} else {
+			o.Messages = t.Messages()
+		}
+	}
+
 	for {
 
 		if false {
@@ -121,22 +179,72 @@ func ChatWithOptions(c *Client, o *ChatOptions) error {
 				continue
 			}
 
-			o.Messages = append(o.Messages, Message{
-				Role:    "user",
-				Content: text,
-			})
+			if o.Tree != nil && strings.HasPrefix(text, "/") {
+				regenerate, err := o.treeCommand(text)
+				if err != nil {
+					fmt.Println(err)
+					continue
+				}
+				previous, sent = "", 0
+				window, covered = nil, 0
+				moderated = len(o.Messages)
+				if err := o.save(nil, nil); err != nil {
+					return err
+				}
+				if !regenerate {
+					continue
+				}
+				// the path ends with a user message to answer, perhaps edited
+				moderated--
+			} else {
+				o.add(Message{
+					Role:    "user",
+					Content: text,
+				})
+			}
 		}
 
+		if !toolCall {
+			turn++
+		}
 		toolCall = false
 
+		// the tree's path matches o.Messages, and keeps what moderation redacts
+		// in place, so switching branches can't bring back the original
+		var path []string
+		if o.Tree != nil && o.Moderation != nil {
+			path = o.Tree.path(o.Tree.Head())
+		}
+		for i := moderated; i < len(o.Messages); i++ {
+			m := &o.Messages[i]
+			var stage string
+			switch m.Role {
+			case "user":
+				stage = StageUserInput
+			case "tool":
+				stage = StageToolOutput
+			default:
+				continue
+			}
+			if err := o.Moderation.check(ctx, c, stage, m); err != nil {
+				return err
+			}
+			if i < len(path) {
+				o.Tree.Nodes[path[i]].Message = *m
+			}
+		}
+		moderated = len(o.Messages)
+
 		chatRequest := ChatRequest{
-			Stream: true,
-			ResponseFormat: &ResponseFormat{
+			Stream:         true,
+			StreamOptions:  &StreamOptions{IncludeUsage: true},
+			Model:          o.Model,
+			ChatParameters: o.ChatParameters,
+		}
+		if len(o.ResponseFormat) > 0 {
+			chatRequest.ResponseFormat = &ResponseFormat{
 				Type: o.ResponseFormat,
-			},
-			Model:       o.Model,
-			Messages:    o.Messages,
-			Temperature: 0.7,
+			}
 		}
 
 		for _, f := range funcs {
@@ -146,14 +254,52 @@ func ChatWithOptions(c *Client, o *ChatOptions) error {
 			})
 		}
 
+		window = append(window, o.Messages[covered:]...)
+		covered = len(o.Messages)
+		if o.History != nil {
+			chatRequest.Messages = window
+			messages, err := o.History.Compact(ctx, c, chatRequest)
+			if err != nil {
+				return err
+			}
+			if messages != nil {
+				// a compacted history can't continue a response chain
+				window = messages
+				previous, sent = "", 0
+			}
+		}
+		chatRequest.Messages = window
+
+		if o.Catalog != nil {
+			if err := o.Catalog.CheckChat(chatRequest); err != nil {
+				return err
+			}
+		}
+
+		if err := c.Costs.Check(); err != nil {
+			return err
+		}
+
 		const endpoint = "chat/completions"
 
 		var r *ChatCompletionResponse
 		var deltas []StreamingChatCompletionResponse
-		if chatRequest.Stream {
+		if o.Responses != nil {
+			x, id, err := o.Responses.round(ctx, c, chatRequest, previous, o.Messages[sent:], func(s string) {
+				if !hold {
+					fmt.Print(s)
+				}
+			})
+			if err != nil {
+				return err
+			}
+			r, previous = x, id
+		} else if chatRequest.Stream {
 			lines, err := c.PostStream(endpoint, chatRequest, func(c StreamingChatCompletionResponse) error {
 				if len(c.Choices) > 0 {
-					fmt.Print(c.Choices[0].Delta.Content)
+					if !hold {
+						fmt.Print(c.Choices[0].Delta.Content)
+					}
 					if tc := c.Choices[0].Delta.ToolCalls; len(tc) > 0 {
 						fmt.Print(tc[0].FunctionCall.Name)
 						fmt.Print(tc[0].FunctionCall.Arguments)
@@ -191,8 +337,17 @@ func ChatWithOptions(c *Client, o *ChatOptions) error {
 			return fmt.Errorf("bad number of choices: %d", len(r.Choices))
 		}
 
+		c.Costs.Record(fmt.Sprintf("turn %d", turn), cmp.Or(r.Model, o.Model), r.Usage)
+
 		choice := r.Choices[0]
-		o.Messages = append(o.Messages, choice.Message)
+		if err := o.Moderation.check(ctx, c, StageAssistant, &choice.Message); err != nil {
+			return err
+		}
+		o.add(choice.Message)
+		sent = len(o.Messages)
+		if err := o.save(chatRequest.Tools, r); err != nil {
+			return err
+		}
 		if o.OneRound {
 			return nil
 		}
@@ -212,21 +367,51 @@ func ChatWithOptions(c *Client, o *ChatOptions) error {
 				if err != nil {
 					return fmt.Errorf("can't run %q: %w", t.FunctionCall.Name, err)
 				}
-				o.Messages = append(o.Messages, Message{
+				o.add(Message{
 					Role:       "tool",
 					ToolCallID: t.ID,
 					Name:       t.FunctionCall.Name,
 					Content:    c,
 				})
 			}
+			if err := o.save(nil, nil); err != nil {
+				return err
+			}
 		default:
 			content := choice.Message.Content
-			if !chatRequest.Stream {
+			if !chatRequest.Stream || hold {
 				fmt.Print(content)
 			}
+			if o.Speech != nil && len(content) > 0 {
+				name, err := o.Speech.speak(ctx, c, content)
+				if err != nil {
+					return fmt.Errorf("can't speak reply: %w", err)
+				}
+				fmt.Printf("\n(spoken to %s)", name)
+			}
 		}
 		fmt.Println()
 	}
 
 	return nil
 }
+
+// appends the message, recording it in the tree, if any
+func (o *ChatOptions) add(m Message) {
+	o.Messages = append(o.Messages, m)
+	if o.Tree != nil {
+		o.Tree.Append(m)
+	}
+}
+
+// updates and saves the session, if any
+func (o *ChatOptions) save(tools []Tool, r *ChatCompletionResponse) error {
+	if o.Session == nil {
+		return nil
+	}
+	o.Session.update(o, tools, r)
+	if err := o.Session.Save(); err != nil {
+		return fmt.Errorf("can't save session: %w", err)
+	}
+	return nil
+}
@@ -3,26 +3,22 @@ package openai
 import (
 	"bufio"
 	"bytes"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
-	"mime"
-	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
 	"reflect"
 	"strings"
 
-	"github.com/google/uuid"
 	"github.com/invopop/jsonschema"
 	"github.com/vincent-petithory/dataurl"
-	"github.com/xoba/open-golang/open"
 )
 
 type Client struct {
 	secretKey string
+	Costs     *CostTracker // if set, tracks and limits what chat requests cost
+	BaseURL   string       // defaults to https://api.openai.com/v1
 }
 
 // new client; if secret key is empty, it will try env var OPENAI_SECRET_KEY
@@ -63,6 +59,12 @@ func checkUnique[T comparable](name string, deltas []StreamingChatCompletionResp
 }
 
 func streamingCombiner(deltas ...StreamingChatCompletionResponse) (*ChatCompletionResponse, error) {
+	// the usage chunk, if requested, comes last and has no choices
+	var usage *Usage
+	if n := len(deltas); n > 0 && len(deltas[n-1].Choices) == 0 && deltas[n-1].Usage != nil {
+		usage = deltas[n-1].Usage
+		deltas = deltas[:n-1]
+	}
 	if len(deltas) == 0 {
 		return nil, fmt.Errorf("no deltas")
 	}
@@ -116,6 +118,7 @@ func streamingCombiner(deltas ...StreamingChatCompletionResponse) (*ChatCompleti
 		Object:  first.Object,
 		Created: first.Created,
 		Model:   first.Model,
+		Usage:   usage,
 	}
 	if false {
 		buf, _ := json.MarshalIndent(deltas, "", "  ")
@@ -161,6 +164,14 @@ func streamingCombiner(deltas ...StreamingChatCompletionResponse) (*ChatCompleti
 			c.Message.Role = sc.Delta.Role
 		}
 		c.Message.Content += sc.Delta.Content
+		c.Message.Refusal += sc.Delta.Refusal
+		if lp := sc.LogProbs; lp != nil {
+			if c.LogProbs == nil {
+				c.LogProbs = new(LogProbs)
+			}
+			c.LogProbs.Content = append(c.LogProbs.Content, lp.Content...)
+			c.LogProbs.Refusal = append(c.LogProbs.Refusal, lp.Refusal...)
+		}
 		c.FinishReason = sc.FinishReason
 	}
 
@@ -173,29 +184,63 @@ func streamingCombiner(deltas ...StreamingChatCompletionResponse) (*ChatCompleti
 }
 
 type Choice struct {
-	Index        int     `json:"index,omitempty"`
-	Message      Message `json:"message,omitempty"`
-	FinishReason string  `json:"finish_reason,omitempty"`
+	Index        int       `json:"index,omitempty"`
+	Message      Message   `json:"message,omitempty"`
+	LogProbs     *LogProbs `json:"logprobs,omitempty"`
+	FinishReason string    `json:"finish_reason,omitempty"`
 }
 
+// a chat message; content is sent as a plain string unless Parts is non-empty,
+// in which case it's sent as an ordered array of content parts (see content.go).
 type Message struct {
 	Role       string     `json:"role"`
 	Content    string     `json:"content"`
+	Refusal    string     `json:"refusal,omitempty"` // set instead of content when the model refuses
+	Parts      []Content  `json:"-"`                 // multimodal content parts: text, images, audio
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"` // in replying with function response
 	Name       string     `json:"name,omitempty"`         // in replying with function response
-
-	//Content    []Content  `json:"content,omitempty"` --- alternate Content struct needed for gpt4v images
 }
 
 type ChatRequest struct {
 	Model          string          `json:"model"`
-	MaxTokens      int             `json:"max_tokens,omitempty"`
-	Temperature    float64         `json:"temperature"`
+	MaxTokens      int             `json:"-"` // sent unless ChatParameters.MaxTokens is set
+	Temperature    float64         `json:"-"` // sent if nonzero, unless ChatParameters.Temperature is set
 	Stream         bool            `json:"stream,omitempty"`
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
 	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
 	Messages       []Message       `json:"messages"`
 	Tools          []Tool          `json:"tools,omitempty"`
+	ChatParameters                 // sampling and other optional parameters, see params.go
+}
+
+// the parameters sent, with MaxTokens and Temperature from either place
+func (r ChatRequest) parameters() ChatParameters {
+	p := r.ChatParameters
+	if p.MaxTokens == 0 {
+		p.MaxTokens = r.MaxTokens
+	}
+	if p.Temperature == nil && r.Temperature != 0 {
+		p.Temperature = Ptr(r.Temperature)
+	}
+	return p
+}
+
+func (r ChatRequest) MarshalJSON() ([]byte, error) {
+	type alias ChatRequest
+	x := alias(r)
+	x.ChatParameters = r.parameters()
+	w := new(bytes.Buffer)
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(x); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(w.Bytes()), nil
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"` // usage arrives in a final chunk without choices
 }
 
 type Tool struct {
@@ -230,70 +275,15 @@ func (a ChatRequest) String() string {
 }
 
 type ResponseFormat struct {
-	Type string `json:"type"`
-}
-
-type Content struct {
-	Type     string `json:"type"`
-	Text     string `json:"text,omitempty"`
-	ImageURL string `json:"image_url,omitempty"`
-}
-
-type DalleResponse struct {
-	Created int
-	Data    []DalleData
-}
-
-type DalleData struct {
-	URL           string `json:"url,omitempty"`
-	B64Json       string `json:"b64_json,omitempty"`
-	RevisedPrompt string `json:"revised_prompt,omitempty"`
-}
-
-func (d DalleResponse) String() string {
-	return toString(d)
+	Type       string            `json:"type"` // text, json_object, or json_schema
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
 }
 
-func Dalle(c *Client) error {
-	var out DalleResponse
-	if err := c.Post("images/generations", ImageRequest{
-		Prompt:         "show me the ocean in coney island, with the beach, bathers, amusement park, and with an airplane in the distance. show the full moon in the sky.",
-		Model:          "dall-e-3",
-		ResponseFormat: "b64_json",
-		Quality:        "hd",
-	}, &out); err != nil {
-		return err
-	}
-	for _, x := range out.Data {
-		if b := x.B64Json; len(b) > 0 {
-			buf, err := base64.StdEncoding.DecodeString(b)
-			if err != nil {
-				return fmt.Errorf("can't decode b64: %w", err)
-			}
-			mimeType := http.DetectContentType(buf)
-			ext, err := mime.ExtensionsByType(mimeType)
-			if err != nil {
-				return err
-			}
-			n := uuid.NewString() + ext[0]
-			if err := os.WriteFile(n, buf, os.ModePerm); err != nil {
-				return err
-			}
-			wd, err := os.Getwd()
-			if err != nil {
-				return err
-			}
-			var u url.URL
-			u.Scheme = "file"
-			u.Path = filepath.Join(wd, n)
-			x.URL = u.String()
-		}
-		fmt.Println(x.RevisedPrompt)
-		if err := open.Run(x.URL); err != nil {
-			return err
-		}
-	}
-	return nil
+type JSONSchemaFormat struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      any    `json:"schema"`
+	Strict      bool   `json:"strict,omitempty"`
 }
 
 type ModelResponse struct {
@@ -325,14 +315,16 @@ func LoadImage(filename string) (*Content, error) {
 	if err != nil {
 		return nil, err
 	}
-	u := dataurl.New(xoba, "image/jpeg")
+	mimeType, err := DetectImageType(xoba)
+	if err != nil {
+		return nil, err
+	}
+	u := dataurl.New(xoba, mimeType)
 	if false {
 		fmt.Println(u)
 	}
-	return &Content{
-		Type:     "image_url",
-		ImageURL: u.String(),
-	}, nil
+	c := ImageContent(u.String(), "")
+	return &c, nil
 }
 
 func loadJoke() (string, error) {
@@ -354,7 +346,20 @@ func loadJoke() (string, error) {
 }
 
 func (c *Client) Get(endpoint string, out any) error {
-	resp, err := c.DoRequest("GET", endpoint, nil, nil)
+	return c.GetContext(context.Background(), endpoint, out)
+}
+
+func (c *Client) GetContext(ctx context.Context, endpoint string, out any) error {
+	return c.doDecode(ctx, "GET", endpoint, out)
+}
+
+func (c *Client) DeleteContext(ctx context.Context, endpoint string, out any) error {
+	return c.doDecode(ctx, "DELETE", endpoint, out)
+}
+
+// a bodiless request whose json response is decoded into out
+func (c *Client) doDecode(ctx context.Context, method, endpoint string, out any) error {
+	resp, err := c.DoRequestContext(ctx, method, endpoint, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -375,9 +380,63 @@ func (s ChatCompletionResponse) String() string {
 }
 
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+}
+
+// subsets of the prompt tokens
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+	AudioTokens  int `json:"audio_tokens,omitempty"`
+}
+
+// subsets of the completion tokens; reasoning tokens are billed as output
+// though they're never returned
+type CompletionTokensDetails struct {
+	ReasoningTokens          int `json:"reasoning_tokens"`
+	AudioTokens              int `json:"audio_tokens,omitempty"`
+	AcceptedPredictionTokens int `json:"accepted_prediction_tokens,omitempty"`
+	RejectedPredictionTokens int `json:"rejected_prediction_tokens,omitempty"`
+}
+
+func (u Usage) CachedTokens() int {
+	if d := u.PromptTokensDetails; d != nil {
+		return d.CachedTokens
+	}
+	return 0
+}
+
+func (u Usage) ReasoningTokens() int {
+	if d := u.CompletionTokensDetails; d != nil {
+		return d.ReasoningTokens
+	}
+	return 0
+}
+
+// adds x's counts to u's
+func (u *Usage) Add(x Usage) {
+	u.PromptTokens += x.PromptTokens
+	u.CompletionTokens += x.CompletionTokens
+	u.TotalTokens += x.TotalTokens
+	if d := x.PromptTokensDetails; d != nil {
+		if u.PromptTokensDetails == nil {
+			u.PromptTokensDetails = new(PromptTokensDetails)
+		}
+		u.PromptTokensDetails.CachedTokens += d.CachedTokens
+		u.PromptTokensDetails.AudioTokens += d.AudioTokens
+	}
+	if d := x.CompletionTokensDetails; d != nil {
+		if u.CompletionTokensDetails == nil {
+			u.CompletionTokensDetails = new(CompletionTokensDetails)
+		}
+		u.CompletionTokensDetails.ReasoningTokens += d.ReasoningTokens
+		u.CompletionTokensDetails.AudioTokens += d.AudioTokens
+		u.CompletionTokensDetails.AcceptedPredictionTokens += d.AcceptedPredictionTokens
+		u.CompletionTokensDetails.RejectedPredictionTokens += d.RejectedPredictionTokens
+	}
 }
 
 type ToolCall struct {
@@ -404,15 +463,6 @@ func toString(i interface{}) string {
 	return w.String()
 }
 
-type ImageRequest struct {
-	Prompt         string `json:"prompt"`
-	Model          string `json:"model,omitempty"`
-	Quality        string `json:"quality,omitempty"`
-	ResponseFormat string `json:"response_format,omitempty"`
-	Size           string `json:"size,omitempty"`
-	Style          string `json:"style,omitempty"`
-}
-
 type SumArgs struct {
 	A, B float64
 }
@@ -0,0 +1,152 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// image detail settings for vision inputs
+const (
+	DetailAuto = "auto"
+	DetailLow  = "low"
+	DetailHigh = "high"
+)
+
+// one part of a multimodal message: text, image_url, or input_audio
+type Content struct {
+	Type       string      `json:"type"`
+	Text       string      `json:"text,omitempty"`
+	ImageURL   *ImageURL   `json:"image_url,omitempty"`
+	InputAudio *InputAudio `json:"input_audio,omitempty"`
+}
+
+type ImageURL struct {
+	URL    string `json:"url"`              // http(s) or data url
+	Detail string `json:"detail,omitempty"` // auto, low, or high
+}
+
+type InputAudio struct {
+	Data   string `json:"data"`   // base64-encoded audio
+	Format string `json:"format"` // wav or mp3
+}
+
+func TextContent(text string) Content {
+	return Content{
+		Type: "text",
+		Text: text,
+	}
+}
+
+// url can be http(s) or a data url; detail may be empty for the api default
+func ImageContent(url, detail string) Content {
+	return Content{
+		Type: "image_url",
+		ImageURL: &ImageURL{
+			URL:    url,
+			Detail: detail,
+		},
+	}
+}
+
+func AudioContent(data []byte, format string) Content {
+	return Content{
+		Type: "input_audio",
+		InputAudio: &InputAudio{
+			Data:   base64.StdEncoding.EncodeToString(data),
+			Format: format,
+		},
+	}
+}
+
+// loads a wav or mp3 file as an input_audio content part
+func LoadAudio(filename string) (*Content, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	switch format {
+	case "wav", "mp3":
+	default:
+		return nil, fmt.Errorf("unsupported audio format: %q", format)
+	}
+	c := AudioContent(buf, format)
+	return &c, nil
+}
+
+// the message's text: either Content, or the concatenation of its text parts
+func (m Message) Text() string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	var list []string
+	for _, p := range m.Parts {
+		if p.Type == "text" {
+			list = append(list, p.Text)
+		}
+	}
+	return strings.Join(list, "\n")
+}
+
+// content is a string, an array of parts, or null for assistant messages
+// having only tool calls.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type alias Message
+	var content any
+	switch {
+	case len(m.Parts) > 0:
+		content = m.Parts
+	case len(m.Content) == 0 && len(m.ToolCalls) > 0:
+		content = nil
+	default:
+		content = m.Content
+	}
+	w := new(bytes.Buffer)
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(struct {
+		alias
+		Content any `json:"content"`
+	}{
+		alias:   alias(m),
+		Content: content,
+	}); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(w.Bytes()), nil
+}
+
+func (m *Message) UnmarshalJSON(buf []byte) error {
+	type alias Message
+	x := struct {
+		*alias
+		Content json.RawMessage `json:"content"`
+	}{
+		alias: (*alias)(m),
+	}
+	if err := json.Unmarshal(buf, &x); err != nil {
+		return err
+	}
+	m.Content = ""
+	m.Parts = nil
+	raw := bytes.TrimSpace(x.Content)
+	switch {
+	case len(raw) == 0, string(raw) == "null":
+	case raw[0] == '"':
+		if err := json.Unmarshal(raw, &m.Content); err != nil {
+			return err
+		}
+	case raw[0] == '[':
+		if err := json.Unmarshal(raw, &m.Parts); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unexpected message content: %s", raw)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package openai
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMessageJSON(t *testing.T) {
+	for _, tt := range []struct {
+		m    Message
+		json string
+	}{
+		{
+			Message{Role: "user", Content: "a < b && c"},
+			`{"role":"user","content":"a < b && c"}`,
+		},
+		{
+			Message{Role: "user"},
+			`{"role":"user","content":""}`,
+		},
+		{
+			Message{Role: "user", Parts: []Content{TextContent("look"), ImageContent("https://example.com/a.png", DetailLow)}},
+			`{"role":"user","content":[{"type":"text","text":"look"},{"type":"image_url","image_url":{"url":"https://example.com/a.png","detail":"low"}}]}`,
+		},
+		{
+			Message{Role: "user", Parts: []Content{AudioContent([]byte("RIFF"), "wav")}},
+			`{"role":"user","content":[{"type":"input_audio","input_audio":{"data":"UklGRg==","format":"wav"}}]}`,
+		},
+		{
+			Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Type: "function", FunctionCall: FunctionCall{Name: "f", Arguments: `{"x":1}`}}}},
+			`{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"f","arguments":"{\"x\":1}"}}],"content":null}`,
+		},
+		{
+			Message{Role: "tool", Content: "42", ToolCallID: "call_1", Name: "f"},
+			`{"role":"tool","tool_call_id":"call_1","name":"f","content":"42"}`,
+		},
+		{
+			Message{Role: "assistant", Refusal: "no"},
+			`{"role":"assistant","refusal":"no","content":""}`,
+		},
+	} {
+		buf, err := tt.m.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != tt.json {
+			t.Errorf("got %s\nwant %s", buf, tt.json)
+		}
+		var m Message
+		if err := json.Unmarshal(buf, &m); err != nil {
+			t.Fatalf("%s: %v", buf, err)
+		}
+		if !reflect.DeepEqual(m, tt.m) {
+			t.Errorf("%s: round trip gave %#v", buf, m)
+		}
+	}
+}
+
+func TestMessageUnmarshal(t *testing.T) {
+	// a message decoded over an old one keeps nothing of its content
+	m := Message{Role: "user", Content: "old", Parts: []Content{TextContent("old")}}
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":null}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, Message{Role: "assistant"}) {
+		t.Errorf("got %#v", m)
+	}
+	if err := json.Unmarshal([]byte(`{"role":"user","content":42}`), &m); err == nil {
+		t.Error("decoded numeric content")
+	}
+}
+
+func TestMessageText(t *testing.T) {
+	for _, tt := range []struct {
+		m    Message
+		want string
+	}{
+		{Message{Content: "plain"}, "plain"},
+		{Message{Content: "ignored", Parts: []Content{TextContent("a"), ImageContent("https://example.com/a.png", ""), TextContent("b")}}, "a\nb"},
+		{Message{Parts: []Content{ImageContent("https://example.com/a.png", "")}}, ""},
+	} {
+		if got := tt.m.Text(); got != tt.want {
+			t.Errorf("%#v: got %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
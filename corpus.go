@@ -0,0 +1,335 @@
+package openai
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// an in-memory keyword index over text documents, ranked by bm25
+type Corpus struct {
+	K1, B     float64 // bm25 parameters, default 1.2 and 0.75
+	Highlight [2]string
+	docs      []corpusDocument
+	postings  map[string][]posting
+	totalLen  int
+}
+
+type corpusDocument struct {
+	name   string
+	text   string
+	tokens []corpusToken
+}
+
+// a stemmed term with its byte range in the document text
+type corpusToken struct {
+	term       string
+	start, end int
+}
+
+type posting struct {
+	doc       int
+	positions []int
+}
+
+type CorpusHit struct {
+	Name    string  `json:"name"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+type CorpusOptions struct {
+	Patterns []string // file name globs to index, defaults to *.txt and *.md
+	Split    string   // "file" (default), "paragraph", or "line"
+}
+
+func NewCorpus() *Corpus {
+	return &Corpus{
+		K1:        1.2,
+		B:         0.75,
+		Highlight: [2]string{"**", "**"},
+		postings:  make(map[string][]posting),
+	}
+}
+
+// indexes matching files under dir
+func LoadCorpusDir(dir string, o CorpusOptions) (*Corpus, error) {
+	return LoadCorpus(os.DirFS(dir), o)
+}
+
+// indexes matching files in a filesystem, such as an embed.FS
+func LoadCorpus(fsys fs.FS, o CorpusOptions) (*Corpus, error) {
+	if len(o.Patterns) == 0 {
+		o.Patterns = []string{"*.txt", "*.md"}
+	}
+	c := NewCorpus()
+	if err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		var match bool
+		for _, pattern := range o.Patterns {
+			ok, err := path.Match(pattern, d.Name())
+			if err != nil {
+				return err
+			}
+			match = match || ok
+		}
+		if !match {
+			return nil
+		}
+		buf, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(buf) {
+			return nil
+		}
+		text := string(buf)
+		switch o.Split {
+		case "", "file":
+			c.Add(p, text)
+		case "paragraph":
+			for i, para := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+				if len(strings.TrimSpace(para)) > 0 {
+					c.Add(fmt.Sprintf("%s#%d", p, i+1), strings.TrimSpace(para))
+				}
+			}
+		case "line":
+			for i, line := range strings.Split(text, "\n") {
+				if line = strings.TrimSpace(line); len(line) > 0 {
+					c.Add(fmt.Sprintf("%s:%d", p, i+1), line)
+				}
+			}
+		default:
+			return fmt.Errorf("bad split: %q", o.Split)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Corpus) Len() int {
+	return len(c.docs)
+}
+
+// adds a document to the index
+func (c *Corpus) Add(name, text string) {
+	d := corpusDocument{
+		name:   name,
+		text:   text,
+		tokens: tokenize(text),
+	}
+	n := len(c.docs)
+	positions := make(map[string][]int)
+	for i, t := range d.tokens {
+		positions[t.term] = append(positions[t.term], i)
+	}
+	for term, list := range positions {
+		c.postings[term] = append(c.postings[term], posting{doc: n, positions: list})
+	}
+	c.docs = append(c.docs, d)
+	c.totalLen += len(d.tokens)
+}
+
+// lowercased, stemmed words and numbers
+func tokenize(text string) []corpusToken {
+	var out []corpusToken
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			out = append(out, corpusToken{
+				term:  stem(strings.ToLower(text[start:end])),
+				start: start,
+				end:   end,
+			})
+			start = -1
+		}
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+		} else {
+			flush(i)
+		}
+	}
+	flush(len(text))
+	return out
+}
+
+// splits a query into loose terms and "quoted phrases", each a list of stemmed terms
+func parseQuery(query string) (terms []string, phrases [][]string) {
+	for i, part := range strings.Split(query, `"`) {
+		var list []string
+		for _, t := range tokenize(part) {
+			list = append(list, t.term)
+		}
+		if i%2 == 1 && len(list) > 1 {
+			phrases = append(phrases, list)
+		}
+		terms = append(terms, list...)
+	}
+	return
+}
+
+// whether the phrase occurs in the document, as consecutive terms
+func (d corpusDocument) hasPhrase(phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(d.tokens); i++ {
+		match := true
+		for j, term := range phrase {
+			if d.tokens[i+j].term != term {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// up to k documents matching the query, best first, or all of them if k <= 0.
+// all quoted phrases must match.
+func (c *Corpus) Search(query string, k int) []CorpusHit {
+	terms, phrases := parseQuery(query)
+	if len(c.docs) == 0 || len(terms) == 0 {
+		return nil
+	}
+	n := float64(len(c.docs))
+	avgLen := float64(c.totalLen) / n
+	scores := make(map[int]float64)
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		list := c.postings[term]
+		idf := math.Log(1 + (n-float64(len(list))+0.5)/(float64(len(list))+0.5))
+		for _, p := range list {
+			tf := float64(len(p.positions))
+			dl := float64(len(c.docs[p.doc].tokens))
+			scores[p.doc] += idf * tf * (c.K1 + 1) / (tf + c.K1*(1-c.B+c.B*dl/avgLen))
+		}
+	}
+	var hits []CorpusHit
+	var docs []int
+	for doc := range scores {
+		ok := true
+		for _, phrase := range phrases {
+			if !c.docs[doc].hasPhrase(phrase) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			docs = append(docs, doc)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if scores[docs[i]] != scores[docs[j]] {
+			return scores[docs[i]] > scores[docs[j]]
+		}
+		return docs[i] < docs[j]
+	})
+	if k > 0 && len(docs) > k {
+		docs = docs[:k]
+	}
+	for _, doc := range docs {
+		hits = append(hits, CorpusHit{
+			Name:    c.docs[doc].name,
+			Score:   scores[doc],
+			Snippet: c.snippet(c.docs[doc], seen, 40),
+		})
+	}
+	return hits
+}
+
+// the window of up to width tokens with the most query terms, with matches highlighted
+func (c *Corpus) snippet(d corpusDocument, terms map[string]bool, width int) string {
+	if len(d.tokens) == 0 {
+		return ""
+	}
+	best, bestCount, count := 0, -1, 0
+	for i, t := range d.tokens {
+		if terms[t.term] {
+			count++
+		}
+		if i >= width && terms[d.tokens[i-width].term] {
+			count--
+		}
+		if start := max(0, i-width+1); count > bestCount {
+			best, bestCount = start, count
+		}
+	}
+	end := min(best+width, len(d.tokens))
+	w := new(strings.Builder)
+	if best > 0 {
+		w.WriteString("…")
+	}
+	pos := d.tokens[best].start
+	for _, t := range d.tokens[best:end] {
+		w.WriteString(d.text[pos:t.start])
+		if terms[t.term] {
+			w.WriteString(c.Highlight[0] + d.text[t.start:t.end] + c.Highlight[1])
+		} else {
+			w.WriteString(d.text[t.start:t.end])
+		}
+		pos = t.end
+	}
+	if end < len(d.tokens) {
+		w.WriteString("…")
+	} else {
+		w.WriteString(d.text[pos:])
+	}
+	return strings.Join(strings.Fields(w.String()), " ")
+}
+
+// a keyword search tool over a local corpus. it needs a corpus, so it isn't
+// one of StandardFuncs; add NewCorpusSearch to ChatOptions.Functions instead.
+type CorpusSearch struct {
+	Query      string
+	MaxResults int
+
+	corpus *Corpus
+}
+
+func NewCorpusSearch(c *Corpus) *CorpusSearch {
+	return &CorpusSearch{corpus: c}
+}
+
+func (CorpusSearch) Description() string {
+	return `searches a local text corpus by keywords, returning the best matching documents with highlighted snippets. words are matched after stemming; put exact phrases in "double quotes".`
+}
+
+func (s *CorpusSearch) Clear() {
+	*s = CorpusSearch{corpus: s.corpus}
+}
+
+func (s CorpusSearch) Run() (string, error) {
+	if s.corpus == nil {
+		return "", fmt.Errorf("no corpus; use NewCorpusSearch")
+	}
+	if s.MaxResults <= 0 {
+		s.MaxResults = 5
+	}
+	hits := s.corpus.Search(s.Query, s.MaxResults)
+	if len(hits) == 0 {
+		return "no matches", nil
+	}
+	return toString(hits), nil
+}
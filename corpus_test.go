@@ -0,0 +1,222 @@
+package openai
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// pairs from martin porter's reference vocabulary and output
+func TestStem(t *testing.T) {
+	for _, tt := range [][2]string{
+		{"caresses", "caress"},
+		{"ponies", "poni"},
+		{"ties", "ti"},
+		{"cats", "cat"},
+		{"feed", "feed"},
+		{"agreed", "agre"},
+		{"plastered", "plaster"},
+		{"bled", "bled"},
+		{"motoring", "motor"},
+		{"sing", "sing"},
+		{"conflated", "conflat"},
+		{"troubled", "troubl"},
+		{"sized", "size"},
+		{"hopping", "hop"},
+		{"tanned", "tan"},
+		{"falling", "fall"},
+		{"hissing", "hiss"},
+		{"fizzed", "fizz"},
+		{"failing", "fail"},
+		{"filing", "file"},
+		{"happy", "happi"},
+		{"sky", "sky"},
+		{"relational", "relat"},
+		{"conditional", "condit"},
+		{"rational", "ration"},
+		{"digitizer", "digit"},
+		{"operator", "oper"},
+		{"feudalism", "feudal"},
+		{"decisiveness", "decis"},
+		{"hopefulness", "hope"},
+		{"callousness", "callous"},
+		{"triplicate", "triplic"},
+		{"formative", "form"},
+		{"formalize", "formal"},
+		{"electrical", "electr"},
+		{"goodness", "good"},
+		{"revival", "reviv"},
+		{"allowance", "allow"},
+		{"inference", "infer"},
+		{"airliner", "airlin"},
+		{"adjustable", "adjust"},
+		{"defensible", "defens"},
+		{"irritant", "irrit"},
+		{"replacement", "replac"},
+		{"dependent", "depend"},
+		{"adoption", "adopt"},
+		{"communism", "commun"},
+		{"activate", "activ"},
+		{"effective", "effect"},
+		{"bowdlerize", "bowdler"},
+		{"probate", "probat"},
+		{"rate", "rate"},
+		{"cease", "ceas"},
+		{"controlling", "control"},
+		{"generalizations", "gener"},
+		{"oscillators", "oscil"},
+		{"running", "run"},
+		{"a", "a"},
+		{"is", "is"},
+		{"naïve", "naïve"},
+	} {
+		if got := stem(tt[0]); got != tt[1] {
+			t.Errorf("%s: got %s, want %s", tt[0], got, tt[1])
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	text := "The Runner's 2 dogs, running!"
+	var terms, words []string
+	for _, x := range tokenize(text) {
+		terms = append(terms, x.term)
+		words = append(words, text[x.start:x.end])
+	}
+	if want := []string{"the", "runner", "s", "2", "dog", "run"}; !slices.Equal(terms, want) {
+		t.Errorf("terms: got %q, want %q", terms, want)
+	}
+	if want := []string{"The", "Runner", "s", "2", "dogs", "running"}; !slices.Equal(words, want) {
+		t.Errorf("words: got %q, want %q", words, want)
+	}
+}
+
+func TestCorpusScore(t *testing.T) {
+	c := NewCorpus()
+	c.Add("a", "apple banana")
+	c.Add("b", "cherry")
+	hits := c.Search("apple", 5)
+	if len(hits) != 1 || hits[0].Name != "a" {
+		t.Fatalf("got %v", hits)
+	}
+	// idf ln(1 + 1.5/1.5), tf 1, length 2 of an average 1.5
+	want := math.Log(2) * 2.2 / (1 + 1.2*(0.25+0.75*2/1.5))
+	if math.Abs(hits[0].Score-want) > 1e-9 {
+		t.Errorf("score %v, want %v", hits[0].Score, want)
+	}
+	if hits[0].Snippet != "**apple** banana" {
+		t.Errorf("snippet %q", hits[0].Snippet)
+	}
+}
+
+func TestCorpusSearch(t *testing.T) {
+	c := NewCorpus()
+	c.Add("fox", "the quick brown fox jumps over the lazy dog")
+	c.Add("dogs", "dogs and more dogs: a dog's life for lazy dogs")
+	c.Add("cat", "a quick cat, brown and lazy")
+	c.Add("runs", "she runs every morning; running keeps her quick")
+	names := func(hits []CorpusHit) (out []string) {
+		for _, h := range hits {
+			out = append(out, h.Name)
+		}
+		return
+	}
+	for _, tt := range []struct {
+		query string
+		k     int
+		want  []string
+	}{
+		{"dog", 5, []string{"dogs", "fox"}},                // more occurrences rank higher
+		{"Running", 5, []string{"runs"}},                   // matched after stemming
+		{"quick brown", 5, []string{"cat", "fox", "runs"}}, // the shorter of the two with both terms first
+		{`"quick brown"`, 5, []string{"fox"}},              // the phrase, not just both words
+		{`"brown fox" lazy`, 5, []string{"fox"}},           // phrases must all match
+		{"lazy", 2, []string{"cat", "fox"}},                // at most k, shortest first
+		{"lazy", 0, []string{"cat", "fox", "dogs"}},        // all of them
+		{"lazy", -1, []string{"cat", "fox", "dogs"}},
+		{"zebra", 5, nil},
+		{"", 5, nil},
+		{"...", 5, nil},
+	} {
+		if got := names(c.Search(tt.query, tt.k)); !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+	if got := NewCorpus().Search("dog", 5); got != nil {
+		t.Errorf("empty corpus found %v", got)
+	}
+}
+
+func TestCorpusSnippet(t *testing.T) {
+	c := NewCorpus()
+	c.Highlight = [2]string{"[", "]"}
+	c.Add("long", strings.Repeat("filler words here ", 30)+"the needle is here "+strings.Repeat("more filler ", 30))
+	hits := c.Search("needle", 1)
+	if len(hits) != 1 {
+		t.Fatalf("got %v", hits)
+	}
+	s := hits[0].Snippet
+	if !strings.HasPrefix(s, "…") || !strings.HasSuffix(s, "…") || !strings.Contains(s, "[needle]") {
+		t.Errorf("snippet %q", s)
+	}
+	if n := len(strings.Fields(s)); n > 42 {
+		t.Errorf("snippet of %d words", n)
+	}
+}
+
+func TestLoadCorpus(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":        {Data: []byte("first paragraph\n\nsecond paragraph\n")},
+		"notes/b.md":   {Data: []byte("one line\ntwo lines\n")},
+		"c.go":         {Data: []byte("package ignored")},
+		"d.txt":        {Data: []byte{0xff, 0xfe}},
+		"e/index.html": {Data: []byte("<p>paragraph</p>")},
+	}
+	for _, tt := range []struct {
+		o    CorpusOptions
+		want []string // hits for "paragraph line"
+	}{
+		{CorpusOptions{}, []string{"notes/b.md", "a.txt"}},
+		{CorpusOptions{Split: "paragraph"}, []string{"a.txt#1", "a.txt#2", "notes/b.md#1"}},
+		{CorpusOptions{Split: "line"}, []string{"a.txt:1", "a.txt:3", "notes/b.md:1", "notes/b.md:2"}},
+		{CorpusOptions{Patterns: []string{"*.html"}}, []string{"e/index.html"}},
+	} {
+		c, err := LoadCorpus(fsys, tt.o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, h := range c.Search("paragraph line", 10) {
+			got = append(got, h.Name)
+		}
+		slices.Sort(got)
+		want := slices.Sorted(slices.Values(tt.want))
+		if !slices.Equal(got, want) {
+			t.Errorf("%+v: got %q, want %q", tt.o, got, want)
+		}
+	}
+	if _, err := LoadCorpus(fsys, CorpusOptions{Split: "word"}); err == nil {
+		t.Error("loaded with a bad split")
+	}
+}
+
+func TestCorpusSearchTool(t *testing.T) {
+	c := NewCorpus()
+	c.Add("a", "apple pie")
+	s := NewCorpusSearch(c)
+	s.Query = "apples"
+	out, err := s.Run()
+	if err != nil || !strings.Contains(out, `"name": "a"`) {
+		t.Errorf("got %s, %v", out, err)
+	}
+	s.Clear()
+	s.Query = "pear"
+	if out, err := s.Run(); err != nil || out != "no matches" {
+		t.Errorf("got %s, %v", out, err)
+	}
+	if _, err := (CorpusSearch{Query: "apple"}).Run(); err == nil {
+		t.Error("searched without a corpus")
+	}
+}
@@ -0,0 +1,207 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// tracks what requests cost, priced by a catalog, and enforces optional limits.
+// set it on a Client to track ChatWithOptions, history summaries, and
+// CompleteJSON; record other calls with Record. it's safe for concurrent use.
+type CostTracker struct {
+	Catalog   *Catalog          // prices; defaults to the embedded catalog
+	MaxCost   float64           // dollars; once spent, further requests are refused
+	MaxTokens int               // total tokens; likewise
+	OnRecord  func(RequestCost) // called after each request is recorded
+
+	mu       sync.Mutex
+	requests []RequestCost
+	total    CostSummary
+}
+
+// one request's usage and cost
+type RequestCost struct {
+	Time            time.Time `json:"time"`
+	Label           string    `json:"label,omitempty"` // what the request was for, like a chat turn
+	Model           string    `json:"model"`
+	InputTokens     int       `json:"input_tokens"`
+	CachedTokens    int       `json:"cached_tokens,omitempty"` // of the input tokens
+	OutputTokens    int       `json:"output_tokens"`
+	ReasoningTokens int       `json:"reasoning_tokens,omitempty"` // of the output tokens
+	Cost            float64   `json:"cost"`
+	Unpriced        bool      `json:"unpriced,omitempty"` // the catalog has no price for the model
+}
+
+func (r RequestCost) String() string {
+	s := fmt.Sprintf("%s: %d in (%d cached), %d out (%d reasoning), $%.6f", r.Model, r.InputTokens, r.CachedTokens, r.OutputTokens, r.ReasoningTokens, r.Cost)
+	if r.Unpriced {
+		s += " (unpriced)"
+	}
+	return s
+}
+
+// totals over some requests
+type CostSummary struct {
+	Requests        int     `json:"requests"`
+	InputTokens     int     `json:"input_tokens"`
+	CachedTokens    int     `json:"cached_tokens"`
+	OutputTokens    int     `json:"output_tokens"`
+	ReasoningTokens int     `json:"reasoning_tokens"`
+	Cost            float64 `json:"cost"`
+	Unpriced        int     `json:"unpriced,omitempty"` // requests whose cost is unknown
+}
+
+func (s CostSummary) String() string {
+	return fmt.Sprintf("%d requests, %d in (%d cached), %d out (%d reasoning), $%.6f", s.Requests, s.InputTokens, s.CachedTokens, s.OutputTokens, s.ReasoningTokens, s.Cost)
+}
+
+func (s *CostSummary) add(r RequestCost) {
+	s.Requests++
+	s.InputTokens += r.InputTokens
+	s.CachedTokens += r.CachedTokens
+	s.OutputTokens += r.OutputTokens
+	s.ReasoningTokens += r.ReasoningTokens
+	s.Cost += r.Cost
+	if r.Unpriced {
+		s.Unpriced++
+	}
+}
+
+// returned once a tracker's limit has been reached
+type BudgetError struct {
+	Spent, MaxCost    float64
+	Tokens, MaxTokens int
+}
+
+func (e *BudgetError) Error() string {
+	if e.MaxTokens > 0 && e.Tokens >= e.MaxTokens {
+		return fmt.Sprintf("token budget exhausted: %d of %d tokens used", e.Tokens, e.MaxTokens)
+	}
+	return fmt.Sprintf("budget exhausted: $%.4f of $%.4f spent", e.Spent, e.MaxCost)
+}
+
+// prices the usage and adds it to the totals; nil trackers and usage are ignored
+func (t *CostTracker) Record(label, model string, u *Usage) RequestCost {
+	if t == nil || u == nil {
+		return RequestCost{}
+	}
+	r := RequestCost{
+		Time:            time.Now(),
+		Label:           label,
+		Model:           model,
+		InputTokens:     u.PromptTokens,
+		CachedTokens:    u.CachedTokens(),
+		OutputTokens:    u.CompletionTokens,
+		ReasoningTokens: u.ReasoningTokens(),
+	}
+	t.mu.Lock()
+	if t.Catalog == nil {
+		t.Catalog = DefaultCatalog()
+	}
+	if m, ok := t.Catalog.Lookup(model); ok && m.InputPrice+m.OutputPrice > 0 {
+		r.Cost = m.Cost(r.InputTokens, r.CachedTokens, r.OutputTokens)
+	} else {
+		r.Unpriced = true
+	}
+	t.requests = append(t.requests, r)
+	t.total.add(r)
+	t.mu.Unlock()
+	if t.OnRecord != nil {
+		t.OnRecord(r)
+	}
+	return r
+}
+
+// a *BudgetError if a limit has been reached, nil otherwise or for a nil tracker
+func (t *CostTracker) Check() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tokens := t.total.InputTokens + t.total.OutputTokens
+	if (t.MaxCost > 0 && t.total.Cost >= t.MaxCost) || (t.MaxTokens > 0 && tokens >= t.MaxTokens) {
+		return &BudgetError{
+			Spent:     t.total.Cost,
+			MaxCost:   t.MaxCost,
+			Tokens:    tokens,
+			MaxTokens: t.MaxTokens,
+		}
+	}
+	return nil
+}
+
+func (t *CostTracker) Total() CostSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// everything recorded, with totals overall, by model, and by label
+type CostReport struct {
+	Total    CostSummary            `json:"total"`
+	Models   map[string]CostSummary `json:"models"`
+	Labels   map[string]CostSummary `json:"labels,omitempty"`
+	Requests []RequestCost          `json:"requests"`
+}
+
+func (t *CostTracker) Report() CostReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := CostReport{
+		Total:    t.total,
+		Models:   make(map[string]CostSummary),
+		Labels:   make(map[string]CostSummary),
+		Requests: append([]RequestCost(nil), t.requests...),
+	}
+	for _, r := range t.requests {
+		m := out.Models[r.Model]
+		m.add(r)
+		out.Models[r.Model] = m
+		if len(r.Label) > 0 {
+			l := out.Labels[r.Label]
+			l.add(r)
+			out.Labels[r.Label] = l
+		}
+	}
+	return out
+}
+
+// a per-model breakdown and the total, most expensive model first
+func (r CostReport) String() string {
+	var models []string
+	for m := range r.Models {
+		models = append(models, m)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return r.Models[models[i]].Cost > r.Models[models[j]].Cost
+	})
+	var s string
+	for _, m := range models {
+		s += fmt.Sprintf("%s: %v\n", m, r.Models[m])
+	}
+	return s + fmt.Sprintf("total: %v", r.Total)
+}
+
+func (t *CostTracker) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(t.Report())
+}
+
+func (t *CostTracker) SaveJSON(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := t.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
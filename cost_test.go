@@ -0,0 +1,155 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func testCostTracker(t *testing.T) *CostTracker {
+	c, err := ParseCatalog([]byte(`[
+		{"id":"big","aliases":["big-2025"],"input_price":2,"cached_input_price":0.5,"output_price":8},
+		{"id":"small","input_price":1,"output_price":4},
+		{"id":"free"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &CostTracker{Catalog: c}
+}
+
+func TestCostRecord(t *testing.T) {
+	for _, tt := range []struct {
+		model string
+		u     Usage
+		want  RequestCost
+	}{
+		{
+			"big",
+			Usage{PromptTokens: 1000, CompletionTokens: 100},
+			RequestCost{Model: "big", InputTokens: 1000, OutputTokens: 100, Cost: (1000*2 + 100*8) / 1e6},
+		},
+		{
+			"big-2025",
+			Usage{
+				PromptTokens:            1000,
+				PromptTokensDetails:     &PromptTokensDetails{CachedTokens: 600},
+				CompletionTokens:        500,
+				CompletionTokensDetails: &CompletionTokensDetails{ReasoningTokens: 300},
+			},
+			// cached tokens are discounted; reasoning tokens are billed as output
+			RequestCost{Model: "big-2025", InputTokens: 1000, CachedTokens: 600, OutputTokens: 500, ReasoningTokens: 300, Cost: (400*2 + 600*0.5 + 500*8) / 1e6},
+		},
+		{
+			"small",
+			Usage{PromptTokens: 1000, PromptTokensDetails: &PromptTokensDetails{CachedTokens: 600}, CompletionTokens: 100},
+			RequestCost{Model: "small", InputTokens: 1000, CachedTokens: 600, OutputTokens: 100, Cost: (1000*1 + 100*4) / 1e6}, // no discount listed
+		},
+		{"free", Usage{PromptTokens: 10}, RequestCost{Model: "free", InputTokens: 10, Unpriced: true}},
+		{"nonesuch", Usage{PromptTokens: 10}, RequestCost{Model: "nonesuch", InputTokens: 10, Unpriced: true}},
+	} {
+		var called RequestCost
+		c := testCostTracker(t)
+		c.OnRecord = func(r RequestCost) { called = r }
+		got := c.Record("x", tt.model, &tt.u)
+		if got.Time.IsZero() || called != got {
+			t.Errorf("%s: time %v, called with %+v", tt.model, got.Time, called)
+		}
+		got.Time = tt.want.Time
+		tt.want.Label = "x"
+		if math.Abs(got.Cost-tt.want.Cost) > 1e-15 {
+			t.Errorf("%s: cost %v, want %v", tt.model, got.Cost, tt.want.Cost)
+		}
+		got.Cost = tt.want.Cost
+		if got != tt.want {
+			t.Errorf("%s: got %+v\nwant %+v", tt.model, got, tt.want)
+		}
+	}
+
+	// nil trackers and usage are ignored
+	var c *CostTracker
+	if r := c.Record("x", "big", &Usage{PromptTokens: 1}); r != (RequestCost{}) {
+		t.Errorf("nil tracker recorded %+v", r)
+	}
+	if err := c.Check(); err != nil {
+		t.Error(err)
+	}
+	c = testCostTracker(t)
+	c.Record("x", "big", nil)
+	if n := c.Total().Requests; n != 0 {
+		t.Errorf("recorded %d requests without usage", n)
+	}
+}
+
+func TestCostBudget(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		maxCost   float64
+		maxTokens int
+		allowed   int // requests before the budget's exhausted
+		message   string
+	}{
+		{"unlimited", 0, 0, 10, ""},
+		{"cost", 0.01, 0, 4, "budget exhausted: $0.0120 of $0.0100 spent"}, // $0.003 each
+		{"exact cost", 0.006, 0, 2, "budget exhausted: $0.0060 of $0.0060 spent"},
+		{"tokens", 0, 2500, 3, "token budget exhausted: 3375 of 2500 tokens used"}, // 1125 each
+		{"both", 1, 2250, 2, "token budget exhausted: 2250 of 2250 tokens used"},
+	} {
+		c := testCostTracker(t)
+		c.MaxCost, c.MaxTokens = tt.maxCost, tt.maxTokens
+		n := 0
+		for ; n < 10; n++ {
+			if err := c.Check(); err != nil {
+				var b *BudgetError
+				if !errors.As(err, &b) || err.Error() != tt.message {
+					t.Errorf("%s: %v", tt.name, err)
+				}
+				break
+			}
+			c.Record("", "big", &Usage{PromptTokens: 1000, CompletionTokens: 125, TotalTokens: 1125})
+		}
+		if n != tt.allowed {
+			t.Errorf("%s: %d requests allowed, want %d", tt.name, n, tt.allowed)
+		}
+	}
+}
+
+func TestCostReport(t *testing.T) {
+	c := testCostTracker(t)
+	c.Record("chat", "big", &Usage{PromptTokens: 1000, PromptTokensDetails: &PromptTokensDetails{CachedTokens: 1000}, CompletionTokens: 100})
+	c.Record("chat", "small", &Usage{PromptTokens: 1000, CompletionTokens: 250})
+	c.Record("", "nonesuch", &Usage{PromptTokens: 1, CompletionTokens: 1, CompletionTokensDetails: &CompletionTokensDetails{ReasoningTokens: 1}})
+	b := new(bytes.Buffer)
+	if err := c.WriteJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	var report map[string]any
+	if err := json.Unmarshal(b.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range report["requests"].([]any) {
+		delete(r.(map[string]any), "time")
+	}
+	buf, _ := json.Marshal(report)
+	const want = `{"labels":{"chat":{"cached_tokens":1000,"cost":0.0033,"input_tokens":2000,"output_tokens":350,"reasoning_tokens":0,"requests":2}},` +
+		`"models":{` +
+		`"big":{"cached_tokens":1000,"cost":0.0013,"input_tokens":1000,"output_tokens":100,"reasoning_tokens":0,"requests":1},` +
+		`"nonesuch":{"cached_tokens":0,"cost":0,"input_tokens":1,"output_tokens":1,"reasoning_tokens":1,"requests":1,"unpriced":1},` +
+		`"small":{"cached_tokens":0,"cost":0.002,"input_tokens":1000,"output_tokens":250,"reasoning_tokens":0,"requests":1}},` +
+		`"requests":[` +
+		`{"cached_tokens":1000,"cost":0.0013,"input_tokens":1000,"label":"chat","model":"big","output_tokens":100},` +
+		`{"cost":0.002,"input_tokens":1000,"label":"chat","model":"small","output_tokens":250},` +
+		`{"cost":0,"input_tokens":1,"model":"nonesuch","output_tokens":1,"reasoning_tokens":1,"unpriced":true}],` +
+		`"total":{"cached_tokens":1000,"cost":0.0033,"input_tokens":2001,"output_tokens":351,"reasoning_tokens":1,"requests":3,"unpriced":1}}`
+	if string(buf) != want {
+		t.Errorf("got  %s\nwant %s", buf, want)
+	}
+	if got, want := c.Report().String(), "small: 1 requests, 1000 in (0 cached), 250 out (0 reasoning), $0.002000\n"+
+		"big: 1 requests, 1000 in (1000 cached), 100 out (0 reasoning), $0.001300\n"+
+		"nonesuch: 1 requests, 1 in (0 cached), 1 out (1 reasoning), $0.000000\n"+
+		"total: 3 requests, 2001 in (1000 cached), 351 out (1 reasoning), $0.003300"; got != want {
+		t.Errorf("got\n%s", got)
+	}
+}
@@ -0,0 +1,255 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// one line of a chat-format fine-tuning dataset
+type FineTuningExample struct {
+	Messages          []Message `json:"messages"`
+	Tools             []Tool    `json:"tools,omitempty"`
+	ParallelToolCalls *bool     `json:"parallel_tool_calls,omitempty"`
+}
+
+// training prices in dollars per million tokens, by base model
+var FineTuningPrices = map[string]float64{
+	"gpt-4.1-2025-04-14":      25,
+	"gpt-4.1-mini-2025-04-14": 5,
+	"gpt-4.1-nano-2025-04-14": 1.5,
+	"gpt-4o-2024-08-06":       25,
+	"gpt-4o-mini-2024-07-18":  3,
+	"gpt-3.5-turbo-0125":      8,
+}
+
+type DatasetOptions struct {
+	Model     string  // base model, for pricing and its encoding
+	Epochs    int     // defaults to the api's automatic choice
+	MaxTokens int     // examples longer than this are truncated in training, defaults to 65536
+	Price     float64 // dollars per million training tokens, overriding FineTuningPrices
+}
+
+// a problem with one example
+type DatasetIssue struct {
+	Line    int
+	Message string
+}
+
+func (i DatasetIssue) String() string {
+	return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+}
+
+type DatasetReport struct {
+	Examples      int
+	Issues        []DatasetIssue
+	Tokens        []int // tokens per valid example, in order
+	TotalTokens   int
+	Estimated     bool // whether token counts are estimates, the model having no known encoding
+	Truncated     int  // examples over MaxTokens
+	Epochs        int
+	BillingTokens int     // tokens trained on, across all epochs
+	Cost          float64 // estimated dollars, zero if the price is unknown
+}
+
+func (r DatasetReport) String() string {
+	w := new(strings.Builder)
+	fmt.Fprintf(w, "%d examples, %d issues\n", r.Examples, len(r.Issues))
+	for _, i := range r.Issues {
+		fmt.Fprintln(w, i)
+	}
+	var estimated string
+	if r.Estimated {
+		estimated = " (estimated at four bytes per token)"
+	}
+	if n := len(r.Tokens); n > 0 {
+		lo, hi := r.Tokens[0], r.Tokens[0]
+		for _, t := range r.Tokens {
+			lo, hi = min(lo, t), max(hi, t)
+		}
+		fmt.Fprintf(w, "tokens per example%s: min %d, mean %d, max %d; %d truncated\n", estimated, lo, r.TotalTokens/n, hi, r.Truncated)
+	}
+	fmt.Fprintf(w, "%d epochs, %d billing tokens%s", r.Epochs, r.BillingTokens, estimated)
+	if r.Cost > 0 {
+		fmt.Fprintf(w, ", ~$%.2f", r.Cost)
+	}
+	return w.String()
+}
+
+func ValidateDatasetFile(filename string, o DatasetOptions) (*DatasetReport, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ValidateDataset(f, o)
+}
+
+// checks a chat-format jsonl dataset before upload, counting tokens and
+// estimating the cost of training on it. counts are exact for models with a
+// known encoding, and estimated otherwise. problems with individual examples
+// are reported as issues; the error is only for failing to read.
+func ValidateDataset(r io.Reader, o DatasetOptions) (*DatasetReport, error) {
+	if o.MaxTokens == 0 {
+		o.MaxTokens = 65536
+	}
+	var out DatasetReport
+	count := approxTokens
+	if e, err := EncodingForModel(o.Model); err == nil {
+		count = e.Count
+	} else {
+		out.Estimated = true
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 64<<20)
+	var line int
+	for s.Scan() {
+		line++
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		out.Examples++
+		var e FineTuningExample
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			out.Issues = append(out.Issues, DatasetIssue{line, fmt.Sprintf("bad json: %v", err)})
+			continue
+		}
+		problems := e.validate()
+		for _, p := range problems {
+			out.Issues = append(out.Issues, DatasetIssue{line, p})
+		}
+		if len(problems) > 0 {
+			continue
+		}
+		n := chatTokens(count, e.Messages, e.Tools)
+		out.Tokens = append(out.Tokens, n)
+		out.TotalTokens += n
+		if n > o.MaxTokens {
+			out.Truncated++
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	out.Epochs = o.Epochs
+	if out.Epochs == 0 {
+		out.Epochs = defaultEpochs(len(out.Tokens))
+	}
+	for _, n := range out.Tokens {
+		out.BillingTokens += min(n, o.MaxTokens)
+	}
+	out.BillingTokens *= out.Epochs
+	price := o.Price
+	if price == 0 {
+		price = FineTuningPrices[o.Model]
+	}
+	out.Cost = float64(out.BillingTokens) * price / 1e6
+	return &out, nil
+}
+
+// the api's automatic epoch count, aiming for between 100 and 25,000 examples
+// seen in total
+func defaultEpochs(n int) int {
+	const (
+		target    = 3
+		minEpochs = 1
+		maxEpochs = 25
+		minSeen   = 100
+		maxSeen   = 25000
+	)
+	if n == 0 {
+		return target
+	}
+	switch {
+	case n*target < minSeen:
+		return min(maxEpochs, int(math.Ceil(float64(minSeen)/float64(n))))
+	case n*target > maxSeen:
+		return max(minEpochs, maxSeen/n)
+	}
+	return target
+}
+
+// structural problems with the example
+func (e FineTuningExample) validate() (out []string) {
+	add := func(format string, a ...any) {
+		out = append(out, fmt.Sprintf(format, a...))
+	}
+	if len(e.Messages) == 0 {
+		add("no messages")
+		return
+	}
+	tools := make(map[string]bool)
+	for i, t := range e.Tools {
+		if t.Type != "function" || t.Function == nil || len(t.Function.Name) == 0 {
+			add("tool %d: not a named function", i)
+			continue
+		}
+		tools[t.Function.Name] = true
+	}
+	pending := make(map[string]bool)
+	var assistant bool
+	for i, m := range e.Messages {
+		if len(pending) > 0 && m.Role != "tool" {
+			add("message %d: %s message before all tool calls were answered", i, m.Role)
+			clear(pending)
+		}
+		switch m.Role {
+		case "system", "developer", "user":
+			if len(m.Content) == 0 && len(m.Parts) == 0 {
+				add("message %d: empty %s message", i, m.Role)
+			}
+		case "assistant":
+			assistant = true
+			if len(m.Content) == 0 && len(m.Parts) == 0 && len(m.ToolCalls) == 0 {
+				add("message %d: assistant message with neither content nor tool calls", i)
+			}
+			for _, t := range m.ToolCalls {
+				switch {
+				case len(t.ID) == 0:
+					add("message %d: tool call without id", i)
+				case pending[t.ID]:
+					add("message %d: duplicate tool call id %q", i, t.ID)
+				}
+				if t.Type != "function" {
+					add("message %d: tool call %q has type %q", i, t.ID, t.Type)
+				}
+				name := t.FunctionCall.Name
+				if len(e.Tools) > 0 && !tools[name] {
+					add("message %d: call to undefined tool %q", i, name)
+				}
+				if !json.Valid([]byte(t.FunctionCall.Arguments)) {
+					add("message %d: tool call %q has invalid json arguments", i, t.ID)
+				}
+				pending[t.ID] = true
+			}
+		case "tool":
+			switch {
+			case len(m.ToolCallID) == 0:
+				add("message %d: tool message without tool_call_id", i)
+			case !pending[m.ToolCallID]:
+				add("message %d: tool message answers unknown call %q", i, m.ToolCallID)
+			}
+			delete(pending, m.ToolCallID)
+		default:
+			add("message %d: unknown role %q", i, m.Role)
+		}
+	}
+	if !assistant {
+		add("no assistant message to train on")
+	}
+	if len(pending) > 0 {
+		add("%d unanswered tool calls", len(pending))
+	}
+	return
+}
+
+// about four bytes of english per token
+func approxTokens(s string) int {
+	return (len(s) + 3) / 4
+}
@@ -0,0 +1,143 @@
+package openai
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const (
+	datasetUser      = `{"role":"user","content":"hi"}`
+	datasetAssistant = `{"role":"assistant","content":"hello"}`
+	datasetCall      = `{"role":"assistant","tool_calls":[{"id":"a","type":"function","function":{"name":"f","arguments":"{}"}}]}`
+	datasetReply     = `{"role":"tool","tool_call_id":"a","content":"42"}`
+	datasetTool      = `{"type":"function","function":{"name":"f","parameters":{}}}`
+)
+
+func example(messages ...string) string {
+	return fmt.Sprintf(`{"messages":[%s]}`, strings.Join(messages, ","))
+}
+
+func TestDatasetIssues(t *testing.T) {
+	for _, tt := range []struct {
+		line string
+		want []string // the issues
+	}{
+		{example(datasetUser, datasetAssistant), nil},
+		{example(`{"role":"system","content":"be brief"}`, datasetUser, datasetCall, datasetReply, datasetAssistant), nil},
+		{fmt.Sprintf(`{"messages":[%s,%s,%s],"tools":[%s]}`, datasetUser, datasetCall, datasetReply, datasetTool), nil},
+		{`{"messages":`, []string{"bad json: unexpected end of JSON input"}},
+		{`{"messages":[]}`, []string{"no messages"}},
+		{example(datasetUser), []string{"no assistant message to train on"}},
+		{example(`{"role":"robot","content":"beep"}`, datasetAssistant), []string{`message 0: unknown role "robot"`}},
+		{example(`{"role":"user","content":""}`, datasetAssistant), []string{"message 0: empty user message"}},
+		{example(datasetUser, `{"role":"assistant"}`), []string{"message 1: assistant message with neither content nor tool calls"}},
+		{
+			example(datasetUser, datasetCall),
+			[]string{"1 unanswered tool calls"},
+		},
+		{
+			example(datasetUser, datasetCall, datasetAssistant),
+			[]string{"message 2: assistant message before all tool calls were answered"},
+		},
+		{
+			example(datasetUser, datasetAssistant, datasetReply),
+			[]string{`message 2: tool message answers unknown call "a"`},
+		},
+		{
+			example(datasetUser, datasetCall, `{"role":"tool","content":"42"}`, datasetAssistant),
+			[]string{"message 2: tool message without tool_call_id", "message 3: assistant message before all tool calls were answered"},
+		},
+		{
+			example(datasetUser, `{"role":"assistant","tool_calls":[{"id":"a","type":"function","function":{"name":"f","arguments":"{}"}},{"id":"a","type":"function","function":{"name":"f","arguments":"{}"}}]}`, datasetReply),
+			[]string{`message 1: duplicate tool call id "a"`},
+		},
+		{
+			example(datasetUser, `{"role":"assistant","tool_calls":[{"type":"code","function":{"name":"f","arguments":"{"}}]}`, `{"role":"tool","tool_call_id":"","content":"x"}`),
+			[]string{
+				"message 1: tool call without id",
+				`message 1: tool call "" has type "code"`,
+				`message 1: tool call "" has invalid json arguments`,
+				"message 2: tool message without tool_call_id",
+			},
+		},
+		{
+			fmt.Sprintf(`{"messages":[%s,%s,%s],"tools":[%s,{"type":"function"}]}`, datasetUser, strings.ReplaceAll(datasetCall, `"f"`, `"g"`), datasetReply, datasetTool),
+			[]string{"tool 1: not a named function", `message 1: call to undefined tool "g"`},
+		},
+	} {
+		r, err := ValidateDataset(strings.NewReader(tt.line+"\n\n"), DatasetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, i := range r.Issues {
+			if i.Line != 1 {
+				t.Errorf("%s: issue on line %d", tt.line, i.Line)
+			}
+			got = append(got, i.Message)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.line, got, tt.want)
+		}
+		counted := 1 // only valid examples are counted
+		if len(tt.want) > 0 {
+			counted = 0
+		}
+		if r.Examples != 1 || len(r.Tokens) != counted {
+			t.Errorf("%s: %d examples, %d counted", tt.line, r.Examples, len(r.Tokens))
+		}
+	}
+}
+
+func TestDatasetTokens(t *testing.T) {
+	data := strings.Repeat(example(datasetUser, datasetAssistant)+"\n", 10) + example(datasetUser) + "\n"
+	e, err := EncodingForModel("gpt-4o-mini-2024-07-18")
+	if err != nil {
+		t.Fatal(err)
+	}
+	per := e.CountChat([]Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}}, nil)
+	// estimates are four bytes per token, after priming and each message's overhead
+	for _, tt := range []struct {
+		o         DatasetOptions
+		per       int
+		estimated bool
+		epochs    int
+		cost      float64
+	}{
+		{DatasetOptions{Model: "gpt-4o-mini-2024-07-18"}, per, false, 10, float64(per*10*10) * 3 / 1e6},
+		{DatasetOptions{Model: "gpt-4o-mini-2024-07-18", Epochs: 2, Price: 100}, per, false, 2, float64(per*10*2) * 100 / 1e6},
+		{DatasetOptions{Model: "davinci-002"}, 3 + (3 + 1 + 1) + (3 + 3 + 2), true, 10, 0},
+		{DatasetOptions{Model: "gpt-4o-mini-2024-07-18", MaxTokens: 5}, per, false, 10, float64(5*10*10) * 3 / 1e6},
+	} {
+		r, err := ValidateDataset(strings.NewReader(data), tt.o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Examples != 11 || len(r.Issues) != 1 || r.Issues[0].Line != 11 {
+			t.Errorf("%+v: %d examples, issues %v", tt.o, r.Examples, r.Issues)
+		}
+		if len(r.Tokens) != 10 || r.Tokens[0] != tt.per || r.TotalTokens != 10*tt.per || r.Estimated != tt.estimated {
+			t.Errorf("%+v: tokens %v, total %d, estimated %v", tt.o, r.Tokens, r.TotalTokens, r.Estimated)
+		}
+		if r.Epochs != tt.epochs || math.Abs(r.Cost-tt.cost) > 1e-12 {
+			t.Errorf("%+v: %d epochs, $%v; want %d, $%v", tt.o, r.Epochs, r.Cost, tt.epochs, tt.cost)
+		}
+		if s := r.String(); strings.Contains(s, "estimated") != tt.estimated {
+			t.Errorf("%+v: report %q", tt.o, s)
+		}
+		if tt.o.MaxTokens > 0 && (r.Truncated != 10 || r.BillingTokens != 10*tt.o.MaxTokens*tt.epochs) {
+			t.Errorf("%+v: %d truncated, %d billing tokens", tt.o, r.Truncated, r.BillingTokens)
+		}
+	}
+}
+
+func TestDefaultEpochs(t *testing.T) {
+	for n, want := range map[int]int{0: 3, 1: 25, 4: 25, 10: 10, 34: 3, 8333: 3, 8334: 2, 30000: 1} {
+		if got := defaultEpochs(n); got != want {
+			t.Errorf("%d examples: %d epochs, want %d", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+)
+
+type EmbeddingRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format,omitempty"` // float or base64
+	User           string   `json:"user,omitempty"`
+}
+
+type EmbeddingResponse struct {
+	Object string      `json:"object"`
+	Data   []Embedding `json:"data"`
+	Model  string      `json:"model"`
+	Usage  *Usage      `json:"usage,omitempty"`
+}
+
+type Embedding struct {
+	Object    string `json:"object"`
+	Index     int    `json:"index"`
+	Embedding Vector `json:"embedding"`
+}
+
+func (r EmbeddingResponse) String() string {
+	return toString(r)
+}
+
+// decodes from either a json array of floats, or base64 little-endian float32s
+func (v *Vector) UnmarshalJSON(buf []byte) error {
+	if len(buf) > 0 && buf[0] == '"' {
+		var s string
+		if err := json.Unmarshal(buf, &s); err != nil {
+			return err
+		}
+		raw, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("can't decode b64 embedding: %w", err)
+		}
+		if len(raw)%4 != 0 {
+			return fmt.Errorf("bad b64 embedding length: %d", len(raw))
+		}
+		out := make(Vector, len(raw)/4)
+		for i := range out {
+			out[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+		}
+		*v = out
+		return nil
+	}
+	var out []float32
+	if err := json.Unmarshal(buf, &out); err != nil {
+		return err
+	}
+	*v = out
+	return nil
+}
+
+type EmbeddingOptions struct {
+	Model       string // defaults to text-embedding-3-small
+	Dimensions  int    // optional shortening, for text-embedding-3 models
+	Base64      bool   // request base64 encoding, which is more compact on the wire
+	BatchSize   int    // max inputs per request, defaults to 2048
+	BatchBytes  int    // max input bytes per request, defaults to 1MB (roughly the api's token limit)
+	Concurrency int    // max concurrent requests, defaults to 4
+}
+
+// embeds all inputs, splitting them into batches that run concurrently; vectors
+// are returned in input order, along with the combined usage.
+func (c *Client) CreateEmbeddings(ctx context.Context, inputs []string, o EmbeddingOptions) ([]Vector, *Usage, error) {
+	if o.Model == "" {
+		o.Model = "text-embedding-3-small"
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = 2048
+	}
+	if o.BatchBytes == 0 {
+		o.BatchBytes = 1 << 20
+	}
+	if o.Concurrency == 0 {
+		o.Concurrency = 4
+	}
+	format := "float"
+	if o.Base64 {
+		format = "base64"
+	}
+
+	type batch struct {
+		start int
+		input []string
+	}
+	var batches []batch
+	var current batch
+	var size int
+	for i, s := range inputs {
+		if len(current.input) > 0 && (len(current.input) == o.BatchSize || size+len(s) > o.BatchBytes) {
+			batches = append(batches, current)
+			current = batch{start: i}
+			size = 0
+		}
+		current.input = append(current.input, s)
+		size += len(s)
+	}
+	if len(current.input) > 0 {
+		batches = append(batches, current)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	out := make([]Vector, len(inputs))
+	var usage Usage
+	var mu sync.Mutex
+	var firstErr error
+	sem := make(chan struct{}, o.Concurrency)
+	var wg sync.WaitGroup
+	for _, b := range batches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			var r EmbeddingResponse
+			err := c.PostContext(ctx, "embeddings", EmbeddingRequest{
+				Model:          o.Model,
+				Input:          b.input,
+				Dimensions:     o.Dimensions,
+				EncodingFormat: format,
+			}, &r)
+			if err == nil && len(r.Data) != len(b.input) {
+				err = fmt.Errorf("got %d embeddings for %d inputs", len(r.Data), len(b.input))
+			}
+			for _, e := range r.Data {
+				if err == nil && (e.Index < 0 || e.Index >= len(b.input)) {
+					err = fmt.Errorf("bad embedding index: %d", e.Index)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			for _, e := range r.Data {
+				out[b.start+e.Index] = e.Embedding
+			}
+			if u := r.Usage; u != nil {
+				usage.PromptTokens += u.PromptTokens
+				usage.TotalTokens += u.TotalTokens
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	return out, &usage, nil
+}
+
+// embeds a single input
+func (c *Client) CreateEmbedding(ctx context.Context, input string, o EmbeddingOptions) (Vector, error) {
+	list, _, err := c.CreateEmbeddings(ctx, []string{input}, o)
+	if err != nil {
+		return nil, err
+	}
+	return list[0], nil
+}
@@ -0,0 +1,130 @@
+package openai
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a stand-in embedding each input "<n> ..." as the vector {n, -n}, recording
+// the batches' sizes and how many ran at once. it replies in reverse index
+// order, and to later batches sooner.
+type embeddingStandIn struct {
+	mu          sync.Mutex
+	batches     []int
+	running     int
+	maxRunning  int
+	failOnInput string
+}
+
+func (s *embeddingStandIn) client(t *testing.T) *Client {
+	return apiStandIn(t, map[string]http.HandlerFunc{
+		"/embeddings": jsonHandler(t, func(r EmbeddingRequest) any {
+			var first int
+			fmt.Sscan(r.Input[0], &first)
+			s.mu.Lock()
+			s.batches = append(s.batches, len(r.Input))
+			s.running++
+			s.maxRunning = max(s.maxRunning, s.running)
+			s.mu.Unlock()
+			time.Sleep(time.Duration(100-first) * 100 * time.Microsecond)
+			s.mu.Lock()
+			s.running--
+			s.mu.Unlock()
+
+			var data []map[string]any
+			for i := len(r.Input) - 1; i >= 0; i-- {
+				var n float32
+				fmt.Sscan(r.Input[i], &n)
+				var v any = []float32{n, -n}
+				if r.EncodingFormat == "base64" {
+					buf := make([]byte, 8)
+					binary.LittleEndian.PutUint32(buf, math.Float32bits(n))
+					binary.LittleEndian.PutUint32(buf[4:], math.Float32bits(-n))
+					v = base64.StdEncoding.EncodeToString(buf)
+				}
+				data = append(data, map[string]any{"object": "embedding", "index": i, "embedding": v})
+			}
+			if slices.Contains(r.Input, s.failOnInput) {
+				data = data[1:]
+			}
+			return map[string]any{
+				"object": "list",
+				"model":  r.Model,
+				"data":   data,
+				"usage":  Usage{PromptTokens: len(r.Input), TotalTokens: len(r.Input)},
+			}
+		}),
+	})
+}
+
+// n inputs "0 ...", "1 ...", each of the given length
+func embeddingInputs(n, length int) []string {
+	var out []string
+	for i := range n {
+		s := fmt.Sprintf("%d ", i)
+		out = append(out, s+strings.Repeat("x", length-len(s)))
+	}
+	return out
+}
+
+func TestCreateEmbeddings(t *testing.T) {
+	for _, tt := range []struct {
+		n, length int
+		o         EmbeddingOptions
+		batches   []int // sizes, in input order
+	}{
+		{10, 5, EmbeddingOptions{}, []int{10}},
+		{10, 5, EmbeddingOptions{BatchSize: 3}, []int{3, 3, 3, 1}},
+		{10, 5, EmbeddingOptions{BatchSize: 3, Concurrency: 1}, []int{3, 3, 3, 1}},
+		{10, 5, EmbeddingOptions{BatchSize: 4, Base64: true, Concurrency: 2}, []int{4, 4, 2}},
+		{10, 10, EmbeddingOptions{BatchBytes: 25}, []int{2, 2, 2, 2, 2}},
+		{3, 30, EmbeddingOptions{BatchBytes: 25}, []int{1, 1, 1}}, // oversized inputs go alone
+		{50, 5, EmbeddingOptions{BatchSize: 1, Concurrency: 3}, slices.Repeat([]int{1}, 50)},
+		{0, 5, EmbeddingOptions{}, nil},
+	} {
+		s := new(embeddingStandIn)
+		inputs := embeddingInputs(tt.n, tt.length)
+		vectors, usage, err := s.client(t).CreateEmbeddings(context.Background(), inputs, tt.o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vectors) != tt.n || usage.PromptTokens != tt.n || usage.TotalTokens != tt.n {
+			t.Errorf("%+v: %d vectors, usage %+v", tt.o, len(vectors), usage)
+		}
+		for i, v := range vectors {
+			if !slices.Equal(v, Vector{float32(i), -float32(i)}) {
+				t.Errorf("%+v: vector %d is %v", tt.o, i, v)
+				break
+			}
+		}
+		got := slices.Clone(s.batches)
+		slices.Sort(got)
+		want := slices.Clone(tt.batches)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("%+v: batches %v, want %v", tt.o, s.batches, tt.batches)
+		}
+		if limit := cmp.Or(tt.o.Concurrency, 4); s.maxRunning > limit {
+			t.Errorf("%+v: %d batches at once", tt.o, s.maxRunning)
+		}
+	}
+
+	s := &embeddingStandIn{failOnInput: "7 xxx"}
+	if _, _, err := s.client(t).CreateEmbeddings(context.Background(), embeddingInputs(10, 5), EmbeddingOptions{BatchSize: 3}); err == nil || !strings.Contains(err.Error(), "got 2 embeddings for 3 inputs") {
+		t.Errorf("got %v", err)
+	}
+	v, err := new(embeddingStandIn).client(t).CreateEmbedding(context.Background(), "7", EmbeddingOptions{})
+	if err != nil || !slices.Equal(v, Vector{7, -7}) {
+		t.Errorf("got %v, %v", v, err)
+	}
+}
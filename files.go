@@ -0,0 +1,178 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// file purposes
+const (
+	PurposeAssistants = "assistants"
+	PurposeBatch      = "batch"
+	PurposeFineTune   = "fine-tune"
+	PurposeVision     = "vision"
+	PurposeUserData   = "user_data"
+	PurposeEvals      = "evals"
+)
+
+type File struct {
+	ID            string `json:"id"`
+	Object        string `json:"object"`
+	Bytes         int64  `json:"bytes"`
+	CreatedAt     int64  `json:"created_at"`
+	ExpiresAt     int64  `json:"expires_at,omitempty"`
+	Filename      string `json:"filename"`
+	Purpose       string `json:"purpose"`
+	Status        string `json:"status,omitempty"`
+	StatusDetails string `json:"status_details,omitempty"`
+}
+
+func (f File) String() string {
+	return toString(f)
+}
+
+// a page of a cursor-paginated list
+type List[T any] struct {
+	Object  string `json:"object"`
+	Data    []T    `json:"data"`
+	FirstID string `json:"first_id,omitempty"`
+	LastID  string `json:"last_id,omitempty"`
+	HasMore bool   `json:"has_more"`
+}
+
+type ListOptions struct {
+	Limit int    // page size
+	After string // cursor: the id of the last item of the previous page
+	Order string // asc or desc, by creation time
+}
+
+func (o ListOptions) values() url.Values {
+	v := make(url.Values)
+	if o.Limit > 0 {
+		v.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if len(o.After) > 0 {
+		v.Set("after", o.After)
+	}
+	if len(o.Order) > 0 {
+		v.Set("order", o.Order)
+	}
+	return v
+}
+
+func withQuery(endpoint string, v url.Values) string {
+	if len(v) == 0 {
+		return endpoint
+	}
+	return endpoint + "?" + v.Encode()
+}
+
+// fetches one page
+func listPage[T any](ctx context.Context, c *Client, endpoint string, v url.Values) (*List[T], error) {
+	var out List[T]
+	if err := c.GetContext(ctx, withQuery(endpoint, v), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// follows the cursor through every page
+func listAll[T any](ctx context.Context, c *Client, endpoint string, v url.Values, id func(T) string) ([]T, error) {
+	var out []T
+	for {
+		page, err := listPage[T](ctx, c, endpoint, v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page.Data...)
+		if !page.HasMore || len(page.Data) == 0 {
+			return out, nil
+		}
+		after := page.LastID
+		if len(after) == 0 {
+			after = id(page.Data[len(page.Data)-1])
+		}
+		v.Set("after", after)
+	}
+}
+
+// uploads a local file
+func (c *Client) UploadFile(ctx context.Context, filename, purpose string) (*File, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return c.UploadFileReader(ctx, filepath.Base(filename), f, purpose)
+}
+
+func (c *Client) UploadFileReader(ctx context.Context, name string, r io.Reader, purpose string) (*File, error) {
+	var out File
+	if err := c.PostMultipart(ctx, "files", url.Values{"purpose": {purpose}}, []FormFile{{
+		Field:    "file",
+		Filename: name,
+		Reader:   r,
+	}}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// lists one page of files, optionally only those with the given purpose
+func (c *Client) ListFiles(ctx context.Context, purpose string, o ListOptions) (*List[File], error) {
+	v := o.values()
+	if len(purpose) > 0 {
+		v.Set("purpose", purpose)
+	}
+	return listPage[File](ctx, c, "files", v)
+}
+
+// lists every file, following pagination
+func (c *Client) AllFiles(ctx context.Context, purpose string) ([]File, error) {
+	v := make(url.Values)
+	if len(purpose) > 0 {
+		v.Set("purpose", purpose)
+	}
+	return listAll(ctx, c, "files", v, func(f File) string { return f.ID })
+}
+
+func (c *Client) RetrieveFile(ctx context.Context, id string) (*File, error) {
+	var out File
+	if err := c.GetContext(ctx, "files/"+id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// streams the file's content to w
+func (c *Client) DownloadFile(ctx context.Context, id string, w io.Writer) error {
+	resp, err := c.DoRequestContext(ctx, "GET", "files/"+id+"/content", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+type deletion struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
+
+func (c *Client) DeleteFile(ctx context.Context, id string) error {
+	var out deletion
+	if err := c.DeleteContext(ctx, "files/"+id, &out); err != nil {
+		return err
+	}
+	if !out.Deleted {
+		return fmt.Errorf("file %q not deleted", id)
+	}
+	return nil
+}
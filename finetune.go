@@ -0,0 +1,238 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+type FineTuningRequest struct {
+	Model           string            `json:"model"`
+	TrainingFile    string            `json:"training_file"`
+	ValidationFile  string            `json:"validation_file,omitempty"`
+	Suffix          string            `json:"suffix,omitempty"` // up to 64 characters, added to the fine-tuned model's name
+	Seed            *int              `json:"seed,omitempty"`
+	Hyperparameters *Hyperparameters  `json:"hyperparameters,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+// each field is either "auto" or a number; nil means auto
+type Hyperparameters struct {
+	BatchSize              any `json:"batch_size,omitempty"`
+	LearningRateMultiplier any `json:"learning_rate_multiplier,omitempty"`
+	NEpochs                any `json:"n_epochs,omitempty"`
+}
+
+type FineTuningJob struct {
+	ID              string            `json:"id"`
+	Object          string            `json:"object"`
+	Model           string            `json:"model"`
+	FineTunedModel  string            `json:"fine_tuned_model,omitempty"`
+	OrganizationID  string            `json:"organization_id,omitempty"`
+	Status          string            `json:"status"` // validating_files, queued, running, succeeded, failed, or cancelled
+	CreatedAt       int64             `json:"created_at"`
+	FinishedAt      int64             `json:"finished_at,omitempty"`
+	EstimatedFinish int64             `json:"estimated_finish,omitempty"`
+	TrainingFile    string            `json:"training_file"`
+	ValidationFile  string            `json:"validation_file,omitempty"`
+	ResultFiles     []string          `json:"result_files,omitempty"`
+	TrainedTokens   int               `json:"trained_tokens,omitempty"`
+	Hyperparameters Hyperparameters   `json:"hyperparameters"`
+	Seed            int               `json:"seed,omitempty"`
+	Suffix          string            `json:"suffix,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	Error           *FineTuningError  `json:"error,omitempty"`
+}
+
+type FineTuningError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+}
+
+func (e *FineTuningError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (j FineTuningJob) String() string {
+	return toString(j)
+}
+
+// whether the job has reached a terminal status
+func (j FineTuningJob) Done() bool {
+	switch j.Status {
+	case "succeeded", "failed", "cancelled":
+		return true
+	}
+	return false
+}
+
+type FineTuningEvent struct {
+	ID        string         `json:"id"`
+	Object    string         `json:"object"`
+	CreatedAt int64          `json:"created_at"`
+	Level     string         `json:"level"` // info, warn, or error
+	Message   string         `json:"message"`
+	Type      string         `json:"type,omitempty"` // message or metrics
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+type FineTuningCheckpoint struct {
+	ID                       string             `json:"id"`
+	Object                   string             `json:"object"`
+	CreatedAt                int64              `json:"created_at"`
+	FineTunedModelCheckpoint string             `json:"fine_tuned_model_checkpoint"`
+	FineTuningJobID          string             `json:"fine_tuning_job_id"`
+	StepNumber               int                `json:"step_number"`
+	Metrics                  map[string]float64 `json:"metrics"`
+}
+
+func (c *Client) CreateFineTuningJob(ctx context.Context, r FineTuningRequest) (*FineTuningJob, error) {
+	var out FineTuningJob
+	if err := c.PostContext(ctx, "fine_tuning/jobs", r, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) RetrieveFineTuningJob(ctx context.Context, id string) (*FineTuningJob, error) {
+	var out FineTuningJob
+	if err := c.GetContext(ctx, "fine_tuning/jobs/"+id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) CancelFineTuningJob(ctx context.Context, id string) (*FineTuningJob, error) {
+	var out FineTuningJob
+	if err := c.PostContext(ctx, "fine_tuning/jobs/"+id+"/cancel", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) ListFineTuningJobs(ctx context.Context, o ListOptions) (*List[FineTuningJob], error) {
+	return listPage[FineTuningJob](ctx, c, "fine_tuning/jobs", o.values())
+}
+
+// lists one page of a job's events, newest first
+func (c *Client) FineTuningEvents(ctx context.Context, id string, o ListOptions) (*List[FineTuningEvent], error) {
+	return listPage[FineTuningEvent](ctx, c, "fine_tuning/jobs/"+id+"/events", o.values())
+}
+
+// lists one page of a job's checkpoints, newest first
+func (c *Client) FineTuningCheckpoints(ctx context.Context, id string, o ListOptions) (*List[FineTuningCheckpoint], error) {
+	return listPage[FineTuningCheckpoint](ctx, c, "fine_tuning/jobs/"+id+"/checkpoints", o.values())
+}
+
+// every checkpoint of a job, following pagination
+func (c *Client) AllFineTuningCheckpoints(ctx context.Context, id string) ([]FineTuningCheckpoint, error) {
+	return listAll(ctx, c, "fine_tuning/jobs/"+id+"/checkpoints", make(url.Values), func(x FineTuningCheckpoint) string { return x.ID })
+}
+
+// the models this organization has fine-tuned
+func (c *Client) FineTunedModels(ctx context.Context) ([]Model, error) {
+	var list ModelResponse
+	if err := c.GetContext(ctx, "models", &list); err != nil {
+		return nil, err
+	}
+	var out []Model
+	for _, m := range list.Data {
+		if strings.HasPrefix(m.ID, "ft:") {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+type WatchOptions struct {
+	PollInterval time.Duration              // defaults to 10s
+	Events       func(FineTuningEvent)      // called once per event, oldest first
+	Checkpoints  func(FineTuningCheckpoint) // called once per checkpoint, oldest first
+	Progress     func(*FineTuningJob)       // called after each poll
+}
+
+// polls a job until it's done, streaming its new events and checkpoints to the
+// callbacks, and returns the final job
+func (c *Client) WatchFineTuningJob(ctx context.Context, id string, o WatchOptions) (*FineTuningJob, error) {
+	interval := o.PollInterval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	seen := make(map[string]bool)
+	for {
+		job, err := c.RetrieveFineTuningJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if o.Events != nil {
+			events, err := newest(seen, func(o ListOptions) (*List[FineTuningEvent], error) {
+				return c.FineTuningEvents(ctx, id, o)
+			}, func(e FineTuningEvent) (string, int64) { return e.ID, e.CreatedAt })
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range events {
+				o.Events(e)
+			}
+		}
+		if o.Checkpoints != nil {
+			checkpoints, err := newest(seen, func(o ListOptions) (*List[FineTuningCheckpoint], error) {
+				return c.FineTuningCheckpoints(ctx, id, o)
+			}, func(x FineTuningCheckpoint) (string, int64) { return x.ID, x.CreatedAt })
+			if err != nil {
+				return nil, err
+			}
+			for _, x := range checkpoints {
+				o.Checkpoints(x)
+			}
+		}
+		if o.Progress != nil {
+			o.Progress(job)
+		}
+		if job.Done() {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// pages through a newest-first list until reaching items already seen,
+// returning the unseen ones oldest first
+func newest[T any](seen map[string]bool, page func(ListOptions) (*List[T], error), key func(T) (string, int64)) ([]T, error) {
+	var out []T
+	o := ListOptions{Limit: 100}
+	for {
+		list, err := page(o)
+		if err != nil {
+			return nil, err
+		}
+		done := !list.HasMore || len(list.Data) == 0
+		for _, x := range list.Data {
+			id, _ := key(x)
+			if seen[id] {
+				done = true
+				break
+			}
+			seen[id] = true
+			out = append(out, x)
+		}
+		if done {
+			break
+		}
+		o.After, _ = key(list.Data[len(list.Data)-1])
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		_, a := key(out[i])
+		_, b := key(out[j])
+		return a < b
+	})
+	return out, nil
+}
@@ -4,9 +4,9 @@ go 1.23.1
 
 require (
 	github.com/google/uuid v1.6.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/invopop/jsonschema v0.12.0
 	github.com/vincent-petithory/dataurl v1.0.0
-	github.com/xoba/open-golang v1.0.0
 )
 
 require (
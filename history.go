@@ -0,0 +1,288 @@
+package openai
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// compacts a conversation's history before each request, so long sessions stay
+// within the model's context window. strategies keep system messages, and keep
+// an assistant's tool calls together with the replies to them.
+type HistoryStrategy interface {
+	// the compacted messages of the request, or nil to leave them as they are
+	Compact(ctx context.Context, c *Client, r ChatRequest) ([]Message, error)
+}
+
+// strategies applied in order, each to the previous one's result
+type HistoryStrategies []HistoryStrategy
+
+func (list HistoryStrategies) Compact(ctx context.Context, c *Client, r ChatRequest) ([]Message, error) {
+	var changed bool
+	for _, s := range list {
+		messages, err := s.Compact(ctx, c, r)
+		if err != nil {
+			return nil, err
+		}
+		if messages != nil {
+			r.Messages = messages
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, nil
+	}
+	return r.Messages, nil
+}
+
+// keeps the prompt within tokens, as the chat command's -context does: trims
+// older tool outputs, optionally summarizes once the prompt passes three
+// quarters of tokens, and then drops the oldest messages
+func ContextLimit(tokens int, summarize bool) HistoryStrategies {
+	h := HistoryStrategies{
+		TrimToolOutputs{Keep: 4, MaxBytes: 2000},
+	}
+	if summarize {
+		h = append(h, Summarize{Tokens: tokens * 3 / 4})
+	}
+	return append(h, TokenBudget{Tokens: tokens})
+}
+
+// keeps about the most recent messages, rounded to whole tool-call groups; the
+// latest group is always kept
+type SlidingWindow struct {
+	Messages int
+}
+
+func (w SlidingWindow) Compact(_ context.Context, _ *Client, r ChatRequest) ([]Message, error) {
+	groups := messageGroups(r.Messages)
+	return keepFrom(r.Messages, groups, recentGroups(r.Messages, groups, w.Messages)), nil
+}
+
+// drops the oldest messages until the request's prompt, tools included, fits in
+// the given number of tokens; the latest group is always kept. it fails for
+// models without a known encoding, rather than guess at the count.
+type TokenBudget struct {
+	Tokens int
+}
+
+func (b TokenBudget) Compact(_ context.Context, _ *Client, r ChatRequest) ([]Message, error) {
+	e, err := EncodingForModel(r.Model)
+	if err != nil {
+		return nil, fmt.Errorf("can't budget history: %w", err)
+	}
+	n := e.CountChat(r.Messages, r.Tools)
+	groups := messageGroups(r.Messages)
+	last := lastGroup(r.Messages, groups)
+	from := 0
+	for ; n > b.Tokens && from < last; from++ {
+		g := groups[from]
+		if r.Messages[g[0]].Role == "system" {
+			continue
+		}
+		n -= e.CountChat(r.Messages[g[0]:g[1]], nil) - 3
+	}
+	return keepFrom(r.Messages, groups, from), nil
+}
+
+// shrinks tool outputs other than the most recent ones to at most MaxBytes,
+// or to a short note if MaxBytes is zero
+type TrimToolOutputs struct {
+	Keep     int // recent tool outputs left alone
+	MaxBytes int
+}
+
+func (t TrimToolOutputs) Compact(_ context.Context, _ *Client, r ChatRequest) ([]Message, error) {
+	var out []Message
+	seen := 0
+	for i := len(r.Messages) - 1; i >= 0; i-- {
+		m := r.Messages[i]
+		if m.Role != "tool" {
+			continue
+		}
+		if seen++; seen <= t.Keep {
+			continue
+		}
+		text := m.Text()
+		if len(text) <= t.MaxBytes || strings.HasSuffix(text, trimmedNote) {
+			continue
+		}
+		if out == nil {
+			out = append([]Message(nil), r.Messages...)
+		}
+		m.Parts = nil
+		m.Content = fmt.Sprintf("%s\n(%d bytes%s", strings.ToValidUTF8(text[:t.MaxBytes], ""), len(text)-t.MaxBytes, trimmedNote)
+		out[i] = m
+	}
+	return out, nil
+}
+
+const trimmedNote = " of output trimmed to save context)"
+
+// once the prompt exceeds Tokens, replaces all but the most recent messages
+// with a summary written by a cheaper model. the summary is a system message,
+// folded into the next summary in turn. nothing is summarized when that
+// couldn't bring the prompt within Tokens, and like TokenBudget it fails for
+// models without a known encoding.
+type Summarize struct {
+	Model  string // the summarizer, defaulting to gpt-4o-mini
+	Tokens int
+	Keep   int // recent messages kept verbatim, defaulting to 10
+}
+
+const summaryPrefix = "summary of the earlier conversation:\n"
+
+func (s Summarize) Compact(ctx context.Context, c *Client, r ChatRequest) ([]Message, error) {
+	e, err := EncodingForModel(r.Model)
+	if err != nil {
+		return nil, fmt.Errorf("can't summarize history: %w", err)
+	}
+	if e.CountChat(r.Messages, r.Tools) <= s.Tokens {
+		return nil, nil
+	}
+	groups := messageGroups(r.Messages)
+	from := recentGroups(r.Messages, groups, cmp.Or(s.Keep, 10))
+	var out, old []Message
+	var fresh bool // whether old holds more than earlier summaries
+	for _, g := range groups[:from] {
+		m := r.Messages[g[0]]
+		summary := m.Role == "system" && strings.HasPrefix(m.Content, summaryPrefix)
+		if m.Role == "system" && !summary {
+			out = append(out, m)
+			continue
+		}
+		if !summary {
+			fresh = true
+		}
+		old = append(old, r.Messages[g[0]:g[1]]...)
+	}
+	if !fresh {
+		return nil, nil
+	}
+	kept := len(out)
+	for _, g := range groups[from:] {
+		out = append(out, r.Messages[g[0]:g[1]]...)
+	}
+	if e.CountChat(out, r.Tools) > s.Tokens {
+		// the recent messages alone are over, so a summary won't help
+		return nil, nil
+	}
+	summary, err := s.summarize(ctx, c, old)
+	if err != nil {
+		return nil, fmt.Errorf("can't summarize history: %w", err)
+	}
+	return slices.Insert(out, kept, Message{Role: "system", Content: summaryPrefix + summary}), nil
+}
+
+func (s Summarize) summarize(ctx context.Context, c *Client, messages []Message) (string, error) {
+	var b strings.Builder
+	for _, m := range messages {
+		text := m.Text()
+		switch {
+		case m.Role == "system":
+			fmt.Fprintf(&b, "%s\n\n", strings.TrimPrefix(text, summaryPrefix))
+		case m.Role == "tool":
+			fmt.Fprintf(&b, "tool %s returned: %s\n\n", m.Name, text)
+		default:
+			if len(text) > 0 {
+				fmt.Fprintf(&b, "%s: %s\n\n", m.Role, text)
+			}
+			for _, t := range m.ToolCalls {
+				fmt.Fprintf(&b, "%s called %s(%s)\n\n", m.Role, t.FunctionCall.Name, t.FunctionCall.Arguments)
+			}
+		}
+	}
+	req := ChatRequest{
+		Model: cmp.Or(s.Model, "gpt-4o-mini"),
+		Messages: []Message{
+			{
+				Role:    "system",
+				Content: "summarize this conversation for the assistant continuing it: the user's goals, decisions made, facts learned, and work still pending. be concise but keep names, numbers, and file paths.",
+			},
+			{
+				Role:    "user",
+				Content: b.String(),
+			},
+		},
+	}
+	if err := c.Costs.Check(); err != nil {
+		return "", err
+	}
+	var resp ChatCompletionResponse
+	if err := c.PostContext(ctx, "chat/completions", req, &resp); err != nil {
+		return "", err
+	}
+	c.Costs.Record("summary", cmp.Or(resp.Model, req.Model), resp.Usage)
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// ranges of messages that stand or fall together: an assistant's tool calls
+// with the replies to them, or any other single message
+func messageGroups(messages []Message) [][2]int {
+	var out [][2]int
+	for i := 0; i < len(messages); {
+		j := i + 1
+		if len(messages[i].ToolCalls) > 0 {
+			for j < len(messages) && messages[j].Role == "tool" {
+				j++
+			}
+		}
+		out = append(out, [2]int{i, j})
+		i = j
+	}
+	return out
+}
+
+// the index of the last group that isn't a system message
+func lastGroup(messages []Message, groups [][2]int) int {
+	for k := len(groups) - 1; k >= 0; k-- {
+		if messages[groups[k][0]].Role != "system" {
+			return k
+		}
+	}
+	return len(groups)
+}
+
+// the first of the most recent groups holding at most n messages, other than
+// system messages, and at least the last group
+func recentGroups(messages []Message, groups [][2]int, n int) int {
+	from := lastGroup(messages, groups)
+	if from == len(groups) {
+		return from
+	}
+	count := groups[from][1] - groups[from][0]
+	for k := from - 1; k >= 0; k-- {
+		g := groups[k]
+		if messages[g[0]].Role == "system" {
+			continue
+		}
+		if count += g[1] - g[0]; count > n {
+			break
+		}
+		from = k
+	}
+	return from
+}
+
+// the messages of groups from the given one on, plus all system messages, or
+// nil if that's all of them
+func keepFrom(messages []Message, groups [][2]int, from int) []Message {
+	var out []Message
+	var dropped bool
+	for k, g := range groups {
+		if k < from && messages[g[0]].Role != "system" {
+			dropped = true
+			continue
+		}
+		out = append(out, messages[g[0]:g[1]]...)
+	}
+	if !dropped {
+		return nil
+	}
+	return out
+}
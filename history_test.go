@@ -0,0 +1,318 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// messages from a list of roles: s for system, u for user, a for assistant, c
+// for an assistant calling a tool, and t for a tool's reply. each message's
+// content is its role and index, like u1.
+func history(roles string) []Message {
+	var out []Message
+	for i, x := range strings.Fields(roles) {
+		m := Message{Content: fmt.Sprintf("%s%d", x, i)}
+		switch x {
+		case "s":
+			m.Role = "system"
+		case "u":
+			m.Role = "user"
+		case "a":
+			m.Role = "assistant"
+		case "c":
+			m.Role = "assistant"
+			m.ToolCalls = []ToolCall{{ID: fmt.Sprint(i), Type: "function"}}
+		case "t":
+			m.Role = "tool"
+		default:
+			panic("bad role: " + x)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func contents(messages []Message) string {
+	var out []string
+	for _, m := range messages {
+		out = append(out, m.Content)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestMessageGroups(t *testing.T) {
+	for _, tt := range []struct {
+		roles string
+		want  [][2]int
+	}{
+		{"", nil},
+		{"s u a", [][2]int{{0, 1}, {1, 2}, {2, 3}}},
+		{"u c t t a", [][2]int{{0, 1}, {1, 4}, {4, 5}}},
+		{"c", [][2]int{{0, 1}}},
+		{"c t u t", [][2]int{{0, 2}, {2, 3}, {3, 4}}},
+	} {
+		if got := messageGroups(history(tt.roles)); !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.roles, got, tt.want)
+		}
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	for _, tt := range []struct {
+		roles string
+		n     int
+		want  string // empty when nothing's dropped
+	}{
+		{"s u a u a", 2, "s0 u3 a4"},
+		{"s u a u a", 3, "s0 a2 u3 a4"},
+		{"s u a", 10, ""},
+		{"s u c t t", 2, "s0 c2 t3 t4"}, // the latest group is kept whole
+		{"u c t a u", 4, "c1 t2 a3 u4"},
+		{"u c t a u", 3, "a3 u4"}, // at most 3, so not the call with its reply
+		{"u s a", 1, "s1 a2"},
+		{"s s", 1, ""},
+	} {
+		messages, err := SlidingWindow{Messages: tt.n}.Compact(context.Background(), nil, ChatRequest{Messages: history(tt.roles)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := contents(messages); got != tt.want {
+			t.Errorf("%q keeping %d: got %q, want %q", tt.roles, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTrimToolOutputs(t *testing.T) {
+	messages := history("u c t a u c t")
+	messages[2].Content = "abcdef"
+	messages[6].Content = "ghijkl"
+	for _, tt := range []struct {
+		keep, max int
+		want      string
+	}{
+		{1, 3, "abc\n(3 bytes of output trimmed to save context)"},
+		{0, 0, "\n(6 bytes of output trimmed to save context)"},
+		{1, 6, ""},
+		{2, 0, ""},
+	} {
+		out, err := TrimToolOutputs{Keep: tt.keep, MaxBytes: tt.max}.Compact(context.Background(), nil, ChatRequest{Messages: messages})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tt.want) == 0 {
+			if out != nil {
+				t.Errorf("keep %d, max %d: trimmed %q", tt.keep, tt.max, contents(out))
+			}
+			continue
+		}
+		if got := out[2].Content; got != tt.want {
+			t.Errorf("keep %d, max %d: got %q, want %q", tt.keep, tt.max, got, tt.want)
+		}
+		if tt.keep > 0 && out[6].Content != "ghijkl" {
+			t.Errorf("keep %d: trimmed the latest output", tt.keep)
+		}
+		if messages[2].Content != "abcdef" {
+			t.Fatal("trimmed the original messages")
+		}
+		// trimming again changes nothing
+		again, err := TrimToolOutputs{Keep: tt.keep, MaxBytes: tt.max}.Compact(context.Background(), nil, ChatRequest{Messages: out})
+		if err != nil || again != nil {
+			t.Errorf("keep %d, max %d: trimmed twice: %q, %v", tt.keep, tt.max, contents(again), err)
+		}
+	}
+}
+
+// sets cl100k_base to a toy encoding with a token per byte, until the test ends
+func byteEncoding(t *testing.T) *Encoding {
+	var tokens []string
+	for i := 0; i < 256; i++ {
+		tokens = append(tokens, string([]byte{byte(i)}))
+	}
+	e, err := ReadEncoding("cl100k_base", strings.NewReader(rankFile(tokens...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodingsMu.RLock()
+	old := encodings[e.Name]
+	encodingsMu.RUnlock()
+	SetEncoding(e)
+	t.Cleanup(func() {
+		encodingsMu.Lock()
+		encodings[e.Name] = old
+		encodingsMu.Unlock()
+	})
+	return e
+}
+
+func TestTokenBudget(t *testing.T) {
+	e := byteEncoding(t)
+	messages := history("s u a u c t a")
+	count := func(roles string) int {
+		var kept []Message
+		for _, x := range strings.Fields(roles) {
+			i := slices.IndexFunc(messages, func(m Message) bool { return m.Content == x })
+			kept = append(kept, messages[i])
+		}
+		return e.CountChat(kept, nil)
+	}
+	for _, tt := range []struct {
+		budget int
+		want   string
+	}{
+		{count("s0 u1 a2 u3 c4 t5 a6"), ""},
+		{count("s0 u1 a2 u3 c4 t5 a6") - 1, "s0 a2 u3 c4 t5 a6"},
+		{count("s0 u3 c4 t5 a6"), "s0 u3 c4 t5 a6"},
+		{count("s0 u3 c4 t5 a6") - 1, "s0 c4 t5 a6"}, // the call and its reply go together
+		{count("s0 a6") - 1, "s0 a6"},                // the latest group is kept regardless
+	} {
+		out, err := TokenBudget{Tokens: tt.budget}.Compact(context.Background(), nil, ChatRequest{Model: "gpt-4", Messages: messages})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := contents(out); got != tt.want {
+			t.Errorf("budget %d: got %q, want %q", tt.budget, got, tt.want)
+		}
+	}
+}
+
+// cases where summarizing can't help, so there's no request to make, and the
+// nil client is never used
+func TestSummarizeNoOp(t *testing.T) {
+	e := byteEncoding(t)
+	summary := Message{Role: "system", Content: summaryPrefix + "earlier things"}
+	recent := history("u a u a")
+	for _, tt := range []struct {
+		name     string
+		messages []Message
+		s        Summarize
+	}{
+		{"under budget", history("s u a u a"), Summarize{Tokens: 1000}},
+		{"only recent", history("s u a u a"), Summarize{Tokens: 1, Keep: 10}},
+		{"only a summary", append([]Message{summary}, recent...), Summarize{Tokens: e.CountChat(recent, nil), Keep: 4}},
+		{"recent over budget", history("s u a u a"), Summarize{Tokens: 1, Keep: 2}},
+	} {
+		out, err := tt.s.Compact(context.Background(), nil, ChatRequest{Model: "gpt-4", Messages: tt.messages})
+		if err != nil || out != nil {
+			t.Errorf("%s: got %q, %v", tt.name, contents(out), err)
+		}
+	}
+}
+
+func TestHistoryUnknownEncoding(t *testing.T) {
+	r := ChatRequest{Model: "davinci", Messages: history("s u a")}
+	if _, err := (TokenBudget{Tokens: 1}).Compact(context.Background(), nil, r); err == nil {
+		t.Error("token budget guessed")
+	}
+	if _, err := (Summarize{Tokens: 1}).Compact(context.Background(), nil, r); err == nil {
+		t.Error("summarize guessed")
+	}
+}
+
+// the chat command's -context strategies, with the real encoding and a
+// stand-in for the summarizer
+func TestContextLimit(t *testing.T) {
+	var messages []Message
+	messages = append(messages, Message{Role: "system", Content: "you are a helpful assistant."})
+	for i := 0; i < 6; i++ {
+		id := fmt.Sprintf("call_%d", i)
+		messages = append(messages,
+			Message{Role: "user", Content: fmt.Sprintf("read file %d, please.", i)},
+			Message{Role: "assistant", ToolCalls: []ToolCall{{ID: id, Type: "function", FunctionCall: FunctionCall{Name: "read", Arguments: fmt.Sprintf(`{"file":%d}`, i)}}}},
+			Message{Role: "tool", ToolCallID: id, Name: "read", Content: strings.Repeat(fmt.Sprintf("line %d of the file. ", i), 120)},
+			Message{Role: "assistant", Content: fmt.Sprintf("file %d is long and repetitive.", i)},
+		)
+	}
+	for i := 0; i < 5; i++ {
+		messages = append(messages,
+			Message{Role: "user", Content: fmt.Sprintf("and what about question %d?", i)},
+			Message{Role: "assistant", Content: fmt.Sprintf("the answer to question %d is %d.", i, i*i)},
+		)
+	}
+	messages = append(messages, Message{Role: "user", Content: "thanks, one more thing."})
+	r := ChatRequest{Model: "gpt-4o", Messages: messages}
+	e, err := EncodingForModel(r.Model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const budget = 2000
+	if n := e.CountChat(messages, nil); n < 2*budget {
+		t.Fatalf("only %d tokens to start with", n)
+	}
+
+	// every tool reply follows its call, and the latest message is kept
+	check := func(out []Message) {
+		t.Helper()
+		if n := e.CountChat(out, nil); n > budget {
+			t.Errorf("%d tokens, over %d", n, budget)
+		}
+		if out[0].Content != messages[0].Content || out[len(out)-1].Content != messages[len(messages)-1].Content {
+			t.Errorf("lost the system or latest message: %q", contents(out))
+		}
+		for i, m := range out {
+			if m.Role == "tool" && (i == 0 || (out[i-1].Role != "tool" && len(out[i-1].ToolCalls) == 0)) {
+				t.Errorf("tool reply %d without its call", i)
+			}
+		}
+	}
+
+	var prompts []string
+	c := apiStandIn(t, map[string]http.HandlerFunc{
+		"/chat/completions": jsonHandler(t, func(r ChatRequest) any {
+			if r.Model != "gpt-4o-mini" || len(r.Messages) != 2 {
+				t.Errorf("summary request: %s with %d messages", r.Model, len(r.Messages))
+			}
+			prompts = append(prompts, r.Messages[len(r.Messages)-1].Content)
+			return ChatCompletionResponse{
+				Model:   r.Model,
+				Choices: []Choice{{Message: Message{Role: "assistant", Content: "the user had six files read."}}},
+			}
+		}),
+	})
+
+	// without summarizing, the oldest messages are dropped
+	out, err := ContextLimit(budget, false).Compact(context.Background(), c, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(out)
+	if len(prompts) > 0 {
+		t.Error("summarized without -summarize")
+	}
+	if len(out) >= len(messages) {
+		t.Errorf("kept all %d messages", len(out))
+	}
+
+	// with it, the older messages are summarized after their tool outputs are trimmed
+	out, err = ContextLimit(budget, true).Compact(context.Background(), c, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(out)
+	if len(prompts) != 1 {
+		t.Fatalf("%d summaries", len(prompts))
+	}
+	if !strings.Contains(prompts[0], trimmedNote) || !strings.Contains(prompts[0], `called read({"file":0})`) {
+		t.Errorf("summarized %q", prompts[0])
+	}
+	want := append([]Message{messages[0], {Role: "system", Content: summaryPrefix + "the user had six files read."}}, messages[len(messages)-10:]...)
+	if got := contents(out); got != contents(want) {
+		t.Errorf("got %q\nwant %q", got, contents(want))
+	}
+
+	// and the summary is folded into the next one
+	r.Messages = slices.Concat(out[:len(out)-10], messages[1:25], messages[len(messages)-10:])
+	if out, err = ContextLimit(budget, true).Compact(context.Background(), c, r); err != nil {
+		t.Fatal(err)
+	}
+	check(out)
+	if len(prompts) != 2 || !strings.HasPrefix(prompts[1], "the user had six files read.") {
+		t.Errorf("%d summaries, the last not folding in the first", len(prompts))
+	}
+	if n := strings.Count(contents(out), summaryPrefix); n != 1 {
+		t.Errorf("%d summaries in %q", n, contents(out))
+	}
+}
@@ -0,0 +1,235 @@
+package openai
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// hierarchical navigable small world graph for approximate nearest neighbor search,
+// over unit vectors using cosine distance. deleted nodes stay in the graph for
+// navigation but are never returned.
+type hnsw struct {
+	M              int // max neighbors per node above level 0; level 0 allows 2*M
+	EfConstruction int
+	Nodes          []hnswNode
+	Entry          int // entry point, or -1 if empty
+	MaxLevel       int
+}
+
+type hnswNode struct {
+	ID      string  // document id
+	Vector  Vector  // normalized
+	Friends [][]int // neighbor node indices per level
+	Deleted bool
+}
+
+func newHNSW() *hnsw {
+	return &hnsw{
+		M:              16,
+		EfConstruction: 200,
+		Entry:          -1,
+	}
+}
+
+type candidate struct {
+	node int
+	dist float64
+}
+
+func (h *hnsw) dist(q Vector, i int) float64 {
+	return 1 - Dot(q, h.Nodes[i].Vector)
+}
+
+func (h *hnsw) maxFriends(level int) int {
+	if level == 0 {
+		return 2 * h.M
+	}
+	return h.M
+}
+
+func (h *hnsw) randomLevel() int {
+	ml := 1 / math.Log(float64(h.M))
+	return int(math.Floor(-math.Log(1-rand.Float64()) * ml))
+}
+
+// number of live nodes
+func (h *hnsw) live() (n int) {
+	for _, x := range h.Nodes {
+		if !x.Deleted {
+			n++
+		}
+	}
+	return
+}
+
+// inserts a normalized vector, returning its node index
+func (h *hnsw) insert(id string, v Vector) int {
+	level := h.randomLevel()
+	n := len(h.Nodes)
+	h.Nodes = append(h.Nodes, hnswNode{
+		ID:      id,
+		Vector:  v,
+		Friends: make([][]int, level+1),
+	})
+	if h.Entry < 0 {
+		h.Entry = n
+		h.MaxLevel = level
+		return n
+	}
+	ep := []candidate{{node: h.Entry, dist: h.dist(v, h.Entry)}}
+	for l := h.MaxLevel; l > level; l-- {
+		ep = h.searchLayer(v, ep, 1, l)[:1]
+	}
+	for l := min(level, h.MaxLevel); l >= 0; l-- {
+		found := h.searchLayer(v, ep, h.EfConstruction, l)
+		neighbors := h.selectNeighbors(found, h.M)
+		for _, c := range neighbors {
+			h.Nodes[n].Friends[l] = append(h.Nodes[n].Friends[l], c.node)
+			h.link(c.node, n, l)
+		}
+		ep = found
+	}
+	if level > h.MaxLevel {
+		h.MaxLevel = level
+		h.Entry = n
+	}
+	return n
+}
+
+// adds a link from a to b at the level, pruning a's neighbors if necessary
+func (h *hnsw) link(a, b, level int) {
+	friends := append(h.Nodes[a].Friends[level], b)
+	if limit := h.maxFriends(level); len(friends) > limit {
+		v := h.Nodes[a].Vector
+		var list []candidate
+		for _, f := range friends {
+			list = append(list, candidate{node: f, dist: h.dist(v, f)})
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].dist < list[j].dist
+		})
+		friends = friends[:0]
+		for _, c := range h.selectNeighbors(list, limit) {
+			friends = append(friends, c.node)
+		}
+	}
+	h.Nodes[a].Friends[level] = friends
+}
+
+// the neighbor selection heuristic: prefer candidates closer to the query than to
+// any already selected neighbor, filling any remaining slots with the closest rest.
+// candidates must be sorted by distance.
+func (h *hnsw) selectNeighbors(candidates []candidate, m int) []candidate {
+	if len(candidates) <= m {
+		return candidates
+	}
+	var out, pruned []candidate
+	for _, c := range candidates {
+		if len(out) == m {
+			break
+		}
+		good := true
+		for _, r := range out {
+			if 1-Dot(h.Nodes[c.node].Vector, h.Nodes[r.node].Vector) < c.dist {
+				good = false
+				break
+			}
+		}
+		if good {
+			out = append(out, c)
+		} else {
+			pruned = append(pruned, c)
+		}
+	}
+	for _, c := range pruned {
+		if len(out) == m {
+			break
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// greedy beam search of one level, returning up to ef candidates sorted by distance
+func (h *hnsw) searchLayer(q Vector, entry []candidate, ef, level int) []candidate {
+	visited := make(map[int]bool)
+	candidates := new(candidateHeap)
+	results := &candidateHeap{max: true}
+	for _, e := range entry {
+		visited[e.node] = true
+		heap.Push(candidates, e)
+		heap.Push(results, e)
+	}
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(candidate)
+		if results.Len() >= ef && c.dist > results.list[0].dist {
+			break
+		}
+		if level >= len(h.Nodes[c.node].Friends) {
+			continue
+		}
+		for _, f := range h.Nodes[c.node].Friends[level] {
+			if visited[f] {
+				continue
+			}
+			visited[f] = true
+			d := h.dist(q, f)
+			if results.Len() < ef || d < results.list[0].dist {
+				heap.Push(candidates, candidate{node: f, dist: d})
+				heap.Push(results, candidate{node: f, dist: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+	out := results.list
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].dist < out[j].dist
+	})
+	return out
+}
+
+// up to k live nodes nearest the normalized query, that pass the accept filter
+func (h *hnsw) search(q Vector, k, ef int, accept func(int) bool) []candidate {
+	if h.Entry < 0 || k <= 0 {
+		return nil
+	}
+	ep := []candidate{{node: h.Entry, dist: h.dist(q, h.Entry)}}
+	for l := h.MaxLevel; l > 0; l-- {
+		ep = h.searchLayer(q, ep, 1, l)[:1]
+	}
+	var out []candidate
+	for _, c := range h.searchLayer(q, ep, max(ef, k), 0) {
+		if h.Nodes[c.node].Deleted || !accept(c.node) {
+			continue
+		}
+		out = append(out, c)
+		if len(out) == k {
+			break
+		}
+	}
+	return out
+}
+
+type candidateHeap struct {
+	list []candidate
+	max  bool
+}
+
+func (h candidateHeap) Len() int { return len(h.list) }
+func (h candidateHeap) Less(i, j int) bool {
+	if h.max {
+		return h.list[i].dist > h.list[j].dist
+	}
+	return h.list[i].dist < h.list[j].dist
+}
+func (h candidateHeap) Swap(i, j int) { h.list[i], h.list[j] = h.list[j], h.list[i] }
+func (h *candidateHeap) Push(x any)   { h.list = append(h.list, x.(candidate)) }
+func (h *candidateHeap) Pop() any {
+	x := h.list[len(h.list)-1]
+	h.list = h.list[:len(h.list)-1]
+	return x
+}
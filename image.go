@@ -0,0 +1,234 @@
+package openai
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/http"
+	"os"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+// options for preparing an image as vision input
+type ImageOptions struct {
+	Detail   string // low, high, or auto; governs resizing and the token estimate
+	Resize   bool   // downscale to the model's tile limits for the given detail
+	Format   string // "jpeg" or "png" to re-encode; empty keeps the original bytes when possible
+	Quality  int    // initial jpeg quality, defaults to 85
+	MaxBytes int    // byte budget for the encoded image; zero means no limit
+}
+
+// an image ready to send, with its estimated token cost
+type PreparedImage struct {
+	Data          []byte
+	MIMEType      string
+	Width, Height int
+	Tokens        int
+}
+
+func (p PreparedImage) DataURL() string {
+	return dataurl.New(p.Data, p.MIMEType).String()
+}
+
+func (p PreparedImage) Content(detail string) Content {
+	return ImageContent(p.DataURL(), detail)
+}
+
+// sniffs the image's mime type, returning an error for formats the api doesn't accept
+func DetectImageType(buf []byte) (string, error) {
+	switch t := http.DetectContentType(buf); t {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return t, nil
+	default:
+		return "", fmt.Errorf("unsupported image type: %q", t)
+	}
+}
+
+// loads an image file as an image_url content part, after preparing it per the options
+func LoadImageOptions(filename string, o ImageOptions) (*Content, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	p, err := PrepareImage(buf, o)
+	if err != nil {
+		return nil, fmt.Errorf("can't prepare %q: %w", filename, err)
+	}
+	c := p.Content(o.Detail)
+	return &c, nil
+}
+
+func PrepareImage(buf []byte, o ImageOptions) (*PreparedImage, error) {
+	mimeType, err := DetectImageType(buf)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(buf))
+	if err != nil {
+		// webp has no standard library decoder, so we can only pass it through
+		if o.Resize || o.Format != "" || (o.MaxBytes > 0 && len(buf) > o.MaxBytes) {
+			return nil, fmt.Errorf("can't decode %s: %w", mimeType, err)
+		}
+		return &PreparedImage{
+			Data:     buf,
+			MIMEType: mimeType,
+		}, nil
+	}
+	w, h := cfg.Width, cfg.Height
+	if o.Resize {
+		w, h = ImageDimensions(w, h, o.Detail)
+	}
+	fits := o.MaxBytes == 0 || len(buf) <= o.MaxBytes
+	if w == cfg.Width && h == cfg.Height && o.Format == "" && fits {
+		return &PreparedImage{
+			Data:     buf,
+			MIMEType: mimeType,
+			Width:    w,
+			Height:   h,
+			Tokens:   ImageTokens(w, h, o.Detail),
+		}, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	format := o.Format
+	if format == "" {
+		format = "jpeg"
+		if mimeType == "image/png" || mimeType == "image/gif" {
+			format = "png"
+		}
+	}
+	quality := o.Quality
+	if quality == 0 {
+		quality = 85
+	}
+	for {
+		out, err := encodeImage(resizeImage(img, w, h), format, quality, o.MaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		if o.MaxBytes == 0 || len(out) <= o.MaxBytes {
+			return &PreparedImage{
+				Data:     out,
+				MIMEType: "image/" + format,
+				Width:    w,
+				Height:   h,
+				Tokens:   ImageTokens(w, h, o.Detail),
+			}, nil
+		}
+		if w <= 64 || h <= 64 {
+			return nil, fmt.Errorf("can't fit image in %d bytes", o.MaxBytes)
+		}
+		w, h = w*3/4, h*3/4
+	}
+}
+
+// encodes the image, stepping jpeg quality down to try to fit in maxBytes
+func encodeImage(img image.Image, format string, quality, maxBytes int) ([]byte, error) {
+	w := new(bytes.Buffer)
+	switch format {
+	case "png":
+		e := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := e.Encode(w, img); err != nil {
+			return nil, err
+		}
+		return w.Bytes(), nil
+	case "jpeg":
+		// jpeg has no alpha, so flatten onto white
+		flat := image.NewRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+		for {
+			w.Reset()
+			if err := jpeg.Encode(w, flat, &jpeg.Options{Quality: quality}); err != nil {
+				return nil, err
+			}
+			if maxBytes == 0 || w.Len() <= maxBytes || quality <= 40 {
+				return w.Bytes(), nil
+			}
+			quality -= 10
+		}
+	default:
+		return nil, fmt.Errorf("unsupported output format: %q", format)
+	}
+}
+
+// downscales by averaging each destination pixel's box of source pixels
+func resizeImage(src image.Image, w, h int) image.Image {
+	b := src.Bounds()
+	if b.Dx() == w && b.Dy() == h {
+		return src
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := y * b.Dy() / h
+		y1 := max((y+1)*b.Dy()/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := x * b.Dx() / w
+			x1 := max((x+1)*b.Dx()/w, x0+1)
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					i := rgba.PixOffset(sx, sy)
+					r += uint32(rgba.Pix[i])
+					g += uint32(rgba.Pix[i+1])
+					bl += uint32(rgba.Pix[i+2])
+					a += uint32(rgba.Pix[i+3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// the dimensions the model will actually see: low detail fits within 512x512;
+// otherwise the image fits within 2048x2048 and then its short side is at most 768.
+// images are never upscaled.
+func ImageDimensions(width, height int, detail string) (int, int) {
+	scale := func(limit float64) {
+		if f := limit / float64(max(width, height)); f < 1 {
+			width, height = scaleDims(width, height, f)
+		}
+	}
+	if detail == DetailLow {
+		scale(512)
+		return width, height
+	}
+	scale(2048)
+	if f := 768 / float64(min(width, height)); f < 1 {
+		width, height = scaleDims(width, height, f)
+	}
+	return width, height
+}
+
+func scaleDims(width, height int, f float64) (int, int) {
+	return max(1, int(math.Round(float64(width)*f))), max(1, int(math.Round(float64(height)*f)))
+}
+
+// estimated prompt tokens for an image of the given size, using the 512px tile
+// accounting of gpt-4o class models. auto detail is priced as high, to be conservative.
+func ImageTokens(width, height int, detail string) int {
+	const base, perTile = 85, 170
+	if detail == DetailLow {
+		return base
+	}
+	w, h := ImageDimensions(width, height, detail)
+	tiles := ((w + 511) / 512) * ((h + 511) / 512)
+	return base + perTile*tiles
+}
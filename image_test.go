@@ -0,0 +1,106 @@
+package openai
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+func TestImageTokens(t *testing.T) {
+	for _, tt := range []struct {
+		width, height int
+		detail        string
+		w, h          int // what the model sees
+		tokens        int
+	}{
+		{1024, 1024, DetailHigh, 768, 768, 765},
+		{1024, 1024, DetailAuto, 768, 768, 765}, // priced as high
+		{1024, 1024, DetailLow, 512, 512, 85},
+		{2048, 4096, DetailHigh, 768, 1536, 1105},
+		{4096, 8192, DetailHigh, 768, 1536, 1105},
+		{4000, 1000, DetailLow, 512, 128, 85},
+		{512, 512, DetailHigh, 512, 512, 255}, // never upscaled
+		{100, 50, DetailLow, 100, 50, 85},
+		{100, 50, DetailHigh, 100, 50, 255},
+		{1, 10000, DetailHigh, 1, 2048, 765},
+	} {
+		w, h := ImageDimensions(tt.width, tt.height, tt.detail)
+		if w != tt.w || h != tt.h {
+			t.Errorf("%dx%d %s: seen as %dx%d, want %dx%d", tt.width, tt.height, tt.detail, w, h, tt.w, tt.h)
+		}
+		if got := ImageTokens(tt.width, tt.height, tt.detail); got != tt.tokens {
+			t.Errorf("%dx%d %s: %d tokens, want %d", tt.width, tt.height, tt.detail, got, tt.tokens)
+		}
+	}
+}
+
+// a png of noise over a gradient, which compresses poorly
+func testPNG(t *testing.T, w, h int) []byte {
+	r := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x + r.Intn(64)), uint8(y + r.Intn(64)), uint8(r.Intn(256)), 255})
+		}
+	}
+	b := new(bytes.Buffer)
+	if err := png.Encode(b, img); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestPrepareImage(t *testing.T) {
+	buf := testPNG(t, 1200, 600)
+	if len(buf) <= 200_000 {
+		t.Fatalf("only %d bytes, within the limits below", len(buf))
+	}
+	for _, tt := range []struct {
+		o        ImageOptions
+		mimeType string // empty if it should fail
+		w, h     int
+	}{
+		{ImageOptions{}, "image/png", 1200, 600},
+		{ImageOptions{Detail: DetailLow, Resize: true}, "image/png", 512, 256},
+		{ImageOptions{Detail: DetailHigh, Resize: true, Format: "jpeg"}, "image/jpeg", 1200, 600},
+		{ImageOptions{Format: "jpeg", MaxBytes: 100_000}, "image/jpeg", 0, 0},
+		{ImageOptions{MaxBytes: 200_000}, "image/png", 0, 0},
+		{ImageOptions{Detail: DetailLow, Resize: true, MaxBytes: 50_000}, "image/png", 0, 0},
+		{ImageOptions{Format: "gif"}, "", 0, 0},
+		{ImageOptions{MaxBytes: 100}, "", 0, 0},
+	} {
+		p, err := PrepareImage(buf, tt.o)
+		if (err == nil) != (len(tt.mimeType) > 0) {
+			t.Errorf("%+v: %v", tt.o, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if p.MIMEType != tt.mimeType {
+			t.Errorf("%+v: %s", tt.o, p.MIMEType)
+		}
+		if tt.o.MaxBytes > 0 && len(p.Data) > tt.o.MaxBytes {
+			t.Errorf("%+v: %d bytes", tt.o, len(p.Data))
+		}
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(p.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Width != p.Width || cfg.Height != p.Height || (tt.w > 0 && (p.Width != tt.w || p.Height != tt.h)) {
+			t.Errorf("%+v: %dx%d, says %dx%d", tt.o, cfg.Width, cfg.Height, p.Width, p.Height)
+		}
+		if p.Tokens != ImageTokens(p.Width, p.Height, tt.o.Detail) {
+			t.Errorf("%+v: %d tokens", tt.o, p.Tokens)
+		}
+		if tt.o == (ImageOptions{}) && !bytes.Equal(p.Data, buf) {
+			t.Error("re-encoded without being asked to")
+		}
+	}
+	if _, err := PrepareImage([]byte("hello"), ImageOptions{}); err == nil {
+		t.Error("prepared text")
+	}
+}
@@ -0,0 +1,234 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/url"
+	"strings"
+)
+
+// upload limits for image edits and variations
+const (
+	MaxDalleImageBytes    = 4 << 20  // dall-e-2 images and all masks
+	MaxGPTImageBytes      = 50 << 20 // each gpt-image input
+	MaxGPTImageEditInputs = 16
+)
+
+// edits one or more images per the prompt; transparent areas of the optional png
+// mask (or of the first image, without one) mark where to edit. dall-e-2 takes
+// a single square png; gpt-image models take up to 16 png, jpeg, or webp images.
+// the model defaults to dall-e-2.
+func (c *Client) EditImages(ctx context.Context, r ImageRequest, images [][]byte, mask []byte) ([]GeneratedImage, *DalleResponse, error) {
+	if len(r.Model) == 0 {
+		r.Model = "dall-e-2"
+	}
+	if len(r.Prompt) == 0 {
+		return nil, nil, fmt.Errorf("no prompt")
+	}
+	if err := r.validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := checkEditImages(r.Model, images, mask); err != nil {
+		return nil, nil, err
+	}
+	field := "image"
+	if isGPTImage(r.Model) {
+		field = "image[]"
+	}
+	var files []FormFile
+	for i, buf := range images {
+		files = append(files, imageFile(field, fmt.Sprintf("image-%d", i), buf))
+	}
+	if len(mask) > 0 {
+		files = append(files, imageFile("mask", "mask", mask))
+	}
+	return c.postImages(ctx, "images/edits", r, files)
+}
+
+// makes variations of a square png; dall-e-2 only, so prompt, quality, and style don't apply
+func (c *Client) ImageVariations(ctx context.Context, r ImageRequest, img []byte) ([]GeneratedImage, *DalleResponse, error) {
+	if len(r.Model) == 0 {
+		r.Model = "dall-e-2"
+	}
+	if r.Model != "dall-e-2" {
+		return nil, nil, fmt.Errorf("variations need dall-e-2, not %s", r.Model)
+	}
+	if len(r.Prompt) > 0 || len(r.Quality) > 0 || len(r.Style) > 0 {
+		return nil, nil, fmt.Errorf("variations take no prompt, quality, or style")
+	}
+	if err := r.validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := checkEditImages(r.Model, [][]byte{img}, nil); err != nil {
+		return nil, nil, err
+	}
+	return c.postImages(ctx, "images/variations", r, []FormFile{imageFile("image", "image", img)})
+}
+
+func imageFile(field, name string, buf []byte) FormFile {
+	t, _ := DetectImageType(buf)
+	if i := strings.LastIndex(t, "/"); i >= 0 {
+		name += "." + t[i+1:]
+	}
+	return FormFile{
+		Field:       field,
+		Filename:    name,
+		ContentType: t,
+		Reader:      bytes.NewReader(buf),
+	}
+}
+
+func (c *Client) postImages(ctx context.Context, endpoint string, r ImageRequest, files []FormFile) ([]GeneratedImage, *DalleResponse, error) {
+	fields, err := r.fields()
+	if err != nil {
+		return nil, nil, err
+	}
+	var out DalleResponse
+	if err := c.PostMultipart(ctx, endpoint, fields, files, &out); err != nil {
+		return nil, nil, err
+	}
+	images, err := out.Images()
+	if err != nil {
+		return nil, nil, err
+	}
+	return images, &out, nil
+}
+
+// the request's non-empty fields as form values
+func (r ImageRequest) fields() (url.Values, error) {
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, err
+	}
+	v := make(url.Values)
+	for k, x := range m {
+		if s := fmt.Sprint(x); len(s) > 0 {
+			v.Set(k, s)
+		}
+	}
+	return v, nil
+}
+
+// checks formats, sizes, and dimensions of edit inputs before upload
+func checkEditImages(model string, images [][]byte, mask []byte) error {
+	gpt := isGPTImage(model)
+	switch {
+	case len(images) == 0:
+		return fmt.Errorf("no images")
+	case !gpt && len(images) > 1:
+		return fmt.Errorf("%s edits a single image", model)
+	case len(images) > MaxGPTImageEditInputs:
+		return fmt.Errorf("too many images: %d > %d", len(images), MaxGPTImageEditInputs)
+	}
+	var first image.Config
+	for i, buf := range images {
+		t, err := DetectImageType(buf)
+		if err != nil {
+			return fmt.Errorf("image %d: %w", i, err)
+		}
+		limit := MaxGPTImageBytes
+		if !gpt {
+			limit = MaxDalleImageBytes
+			if t != "image/png" {
+				return fmt.Errorf("image %d: %s needs png, not %s", i, model, t)
+			}
+		} else if t == "image/gif" {
+			return fmt.Errorf("image %d: gif isn't supported for edits", i)
+		}
+		if len(buf) > limit {
+			return fmt.Errorf("image %d: %d bytes is over the %d byte limit", i, len(buf), limit)
+		}
+		if t == "image/webp" {
+			continue
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(buf))
+		if err != nil {
+			return fmt.Errorf("image %d: %w", i, err)
+		}
+		if !gpt && config.Width != config.Height {
+			return fmt.Errorf("image %d: %s needs a square image, not %dx%d", i, model, config.Width, config.Height)
+		}
+		if i == 0 {
+			first = config
+		}
+	}
+	if len(mask) == 0 {
+		return nil
+	}
+	if t, _ := DetectImageType(mask); t != "image/png" {
+		return fmt.Errorf("mask must be png")
+	}
+	if len(mask) > MaxDalleImageBytes {
+		return fmt.Errorf("mask: %d bytes is over the %d byte limit", len(mask), MaxDalleImageBytes)
+	}
+	config, err := png.DecodeConfig(bytes.NewReader(mask))
+	if err != nil {
+		return fmt.Errorf("mask: %w", err)
+	}
+	switch config.ColorModel {
+	case color.RGBAModel, color.NRGBAModel, color.RGBA64Model, color.NRGBA64Model:
+	default:
+		return fmt.Errorf("mask needs an alpha channel")
+	}
+	if first.Width > 0 && (config.Width != first.Width || config.Height != first.Height) {
+		return fmt.Errorf("mask is %dx%d but the image is %dx%d", config.Width, config.Height, first.Width, first.Height)
+	}
+	return nil
+}
+
+// a png mask that's opaque except for the given rectangles, which are
+// transparent and so mark the areas to edit
+func MaskFromRects(width, height int, rects ...image.Rectangle) ([]byte, error) {
+	m := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(m, m.Bounds(), image.NewUniform(color.NRGBA{A: 255}), image.Point{}, draw.Src)
+	for _, r := range rects {
+		draw.Draw(m, r.Intersect(m.Bounds()), image.Transparent, image.Point{}, draw.Src)
+	}
+	return maskPNG(m)
+}
+
+// converts an existing image into a png mask of the given size. bright pixels,
+// or transparent ones, mark the areas to edit, so a white-on-black drawing or an
+// image with its edit area erased both work.
+func MaskFromImage(buf []byte, width, height int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	src = resizeImage(src, width, height)
+	b := src.Bounds()
+	m := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBAModel.Convert(src.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			luma := (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+			if c.A < 128 || luma >= 128 {
+				continue
+			}
+			m.SetNRGBA(x, y, color.NRGBA{A: 255})
+		}
+	}
+	return maskPNG(m)
+}
+
+// encodes the mask, which png would write without alpha if it were opaque
+func maskPNG(m *image.NRGBA) ([]byte, error) {
+	if m.Opaque() {
+		return nil, fmt.Errorf("mask has no area to edit")
+	}
+	w := new(bytes.Buffer)
+	if err := png.Encode(w, m); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
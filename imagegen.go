@@ -0,0 +1,228 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type ImageRequest struct {
+	Prompt            string `json:"prompt"`
+	Model             string `json:"model,omitempty"` // dall-e-2, dall-e-3 (default), or gpt-image-1
+	N                 int    `json:"n,omitempty"`     // dall-e-3 only makes one
+	Quality           string `json:"quality,omitempty"`
+	ResponseFormat    string `json:"response_format,omitempty"` // url or b64_json; dall-e only, gpt-image always returns base64
+	Size              string `json:"size,omitempty"`
+	Style             string `json:"style,omitempty"`              // vivid or natural; dall-e-3 only
+	Background        string `json:"background,omitempty"`         // transparent, opaque, or auto; gpt-image only
+	OutputFormat      string `json:"output_format,omitempty"`      // png, jpeg, or webp; gpt-image only
+	OutputCompression *int   `json:"output_compression,omitempty"` // 0 to 100 for jpeg and webp; gpt-image only
+	Moderation        string `json:"moderation,omitempty"`         // low or auto; gpt-image only
+	User              string `json:"user,omitempty"`
+}
+
+type DalleResponse struct {
+	Created      int64       `json:"created"`
+	Data         []DalleData `json:"data"`
+	Background   string      `json:"background,omitempty"`
+	OutputFormat string      `json:"output_format,omitempty"`
+	Quality      string      `json:"quality,omitempty"`
+	Size         string      `json:"size,omitempty"`
+	Usage        *ImageUsage `json:"usage,omitempty"` // gpt-image only
+}
+
+type DalleData struct {
+	URL           string `json:"url,omitempty"`
+	B64Json       string `json:"b64_json,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+}
+
+type ImageUsage struct {
+	InputTokens        int `json:"input_tokens"`
+	OutputTokens       int `json:"output_tokens"`
+	TotalTokens        int `json:"total_tokens"`
+	InputTokensDetails struct {
+		TextTokens  int `json:"text_tokens"`
+		ImageTokens int `json:"image_tokens"`
+	} `json:"input_tokens_details"`
+}
+
+func (d DalleResponse) String() string {
+	return toString(d)
+}
+
+// an image from the api: decoded data, or a url if that's what was asked for
+type GeneratedImage struct {
+	Data          []byte
+	MIMEType      string
+	URL           string
+	RevisedPrompt string
+}
+
+func isGPTImage(model string) bool {
+	return strings.HasPrefix(model, "gpt-image")
+}
+
+// image sizes by model, for the models that constrain them
+var imageSizes = map[string][]string{
+	"dall-e-2":  {"256x256", "512x512", "1024x1024"},
+	"dall-e-3":  {"1024x1024", "1792x1024", "1024x1792"},
+	"gpt-image": {"auto", "1024x1024", "1536x1024", "1024x1536"},
+}
+
+// checks the request's options against what its model supports
+func (r ImageRequest) validate() error {
+	model := r.Model
+	if isGPTImage(model) {
+		model = "gpt-image"
+		switch {
+		case len(r.ResponseFormat) > 0:
+			return fmt.Errorf("gpt-image models don't take a response format; images are always base64")
+		case len(r.Style) > 0:
+			return fmt.Errorf("style is only for dall-e-3")
+		}
+	} else {
+		switch {
+		case len(r.Background) > 0, len(r.OutputFormat) > 0, r.OutputCompression != nil, len(r.Moderation) > 0:
+			return fmt.Errorf("background, output format, compression, and moderation are only for gpt-image models")
+		case model == "dall-e-3" && r.N > 1:
+			return fmt.Errorf("dall-e-3 makes one image per request")
+		case model == "dall-e-2" && len(r.Style) > 0:
+			return fmt.Errorf("style is only for dall-e-3")
+		}
+	}
+	if sizes, ok := imageSizes[model]; ok && len(r.Size) > 0 && !slices.Contains(sizes, r.Size) {
+		return fmt.Errorf("bad size %q for %s; want one of %s", r.Size, model, strings.Join(sizes, ", "))
+	}
+	if c := r.OutputCompression; c != nil {
+		if *c < 0 || *c > 100 {
+			return fmt.Errorf("bad compression: %d", *c)
+		}
+		if r.OutputFormat != "jpeg" && r.OutputFormat != "webp" {
+			return fmt.Errorf("compression needs jpeg or webp output")
+		}
+	}
+	return nil
+}
+
+// generates images for the prompt, returning them decoded along with the raw
+// response (which has token usage for gpt-image); the model defaults to dall-e-3
+func (c *Client) GenerateImages(ctx context.Context, r ImageRequest) ([]GeneratedImage, *DalleResponse, error) {
+	if len(r.Model) == 0 {
+		r.Model = "dall-e-3"
+	}
+	if err := r.validate(); err != nil {
+		return nil, nil, err
+	}
+	var out DalleResponse
+	if err := c.PostContext(ctx, "images/generations", r, &out); err != nil {
+		return nil, nil, err
+	}
+	images, err := out.Images()
+	if err != nil {
+		return nil, nil, err
+	}
+	return images, &out, nil
+}
+
+// decodes the response's images
+func (d DalleResponse) Images() ([]GeneratedImage, error) {
+	var out []GeneratedImage
+	for _, x := range d.Data {
+		g := GeneratedImage{
+			URL:           x.URL,
+			RevisedPrompt: x.RevisedPrompt,
+		}
+		if len(x.B64Json) > 0 {
+			buf, err := base64.StdEncoding.DecodeString(x.B64Json)
+			if err != nil {
+				return nil, fmt.Errorf("can't decode b64: %w", err)
+			}
+			g.Data = buf
+			g.MIMEType = http.DetectContentType(buf)
+		}
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// what's saved alongside each image
+type ImageMetadata struct {
+	File          string       `json:"file"`
+	Prompt        string       `json:"prompt"`
+	RevisedPrompt string       `json:"revised_prompt,omitempty"`
+	URL           string       `json:"url,omitempty"`
+	Request       ImageRequest `json:"request"`
+}
+
+// writes the images into dir, downloading any that are urls, each with a json
+// metadata sidecar of the same name; returns the image filenames
+func SaveImages(ctx context.Context, dir string, r ImageRequest, images []GeneratedImage) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, g := range images {
+		if len(g.Data) == 0 && len(g.URL) > 0 {
+			if err := g.download(ctx); err != nil {
+				return nil, err
+			}
+		}
+		ext := ".png"
+		if list, _ := mime.ExtensionsByType(g.MIMEType); len(list) > 0 {
+			ext = list[0]
+		}
+		base := filepath.Join(dir, uuid.NewString())
+		name := base + ext
+		if err := os.WriteFile(name, g.Data, 0644); err != nil {
+			return nil, err
+		}
+		meta, err := json.MarshalIndent(ImageMetadata{
+			File:          filepath.Base(name),
+			Prompt:        r.Prompt,
+			RevisedPrompt: g.RevisedPrompt,
+			URL:           g.URL,
+			Request:       r,
+		}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(base+".json", meta, 0644); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, nil
+}
+
+func (g *GeneratedImage) download(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", g.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("can't download image: %s", resp.Status)
+	}
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	g.Data = buf
+	g.MIMEType = http.DetectContentType(buf)
+	return nil
+}
@@ -0,0 +1,123 @@
+package openai
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+type LogProbs struct {
+	Content []TokenLogProb `json:"content"`
+	Refusal []TokenLogProb `json:"refusal,omitempty"`
+}
+
+type TokenLogProb struct {
+	Token       string       `json:"token"`
+	LogProb     float64      `json:"logprob"`
+	Bytes       []int        `json:"bytes,omitempty"`
+	TopLogProbs []TopLogProb `json:"top_logprobs,omitempty"`
+}
+
+type TopLogProb struct {
+	Token   string  `json:"token"`
+	LogProb float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes,omitempty"`
+}
+
+func (l LogProbs) String() string {
+	return toString(l)
+}
+
+// the token's probability, in [0,1]
+func (t TokenLogProb) Probability() float64 {
+	return math.Exp(t.LogProb)
+}
+
+// the other candidate tokens the model considered, most likely first
+func (t TokenLogProb) Alternatives() []TopLogProb {
+	var out []TopLogProb
+	for _, x := range t.TopLogProbs {
+		if x.Token != t.Token {
+			out = append(out, x)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].LogProb > out[j].LogProb
+	})
+	return out
+}
+
+// sum of the content tokens' log probabilities
+func (l LogProbs) TotalLogProb() float64 {
+	var sum float64
+	for _, t := range l.Content {
+		sum += t.LogProb
+	}
+	return sum
+}
+
+// exp of the negative mean log probability; 1 means complete certainty
+func (l LogProbs) Perplexity() float64 {
+	if len(l.Content) == 0 {
+		return math.NaN()
+	}
+	return math.Exp(-l.TotalLogProb() / float64(len(l.Content)))
+}
+
+// probability of each content token, in order
+func (l LogProbs) Confidences() []float64 {
+	out := make([]float64, len(l.Content))
+	for i, t := range l.Content {
+		out[i] = t.Probability()
+	}
+	return out
+}
+
+// content tokens whose probability is below the threshold, useful for flagging
+// possible hallucinations
+func (l LogProbs) LowConfidence(threshold float64) []TokenLogProb {
+	var out []TokenLogProb
+	for _, t := range l.Content {
+		if t.Probability() < threshold {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// normalized probabilities over the given labels, from the first content token's
+// top logprobs; a candidate counts toward a label if it's a case-insensitive prefix
+// of it. request with LogProbs and TopLogProbs set, and instruct the model to answer
+// with just the label.
+func (l LogProbs) Classify(labels ...string) map[string]float64 {
+	out := make(map[string]float64)
+	if len(l.Content) == 0 {
+		return out
+	}
+	first := l.Content[0]
+	candidates := first.TopLogProbs
+	if len(candidates) == 0 {
+		candidates = []TopLogProb{{Token: first.Token, LogProb: first.LogProb}}
+	}
+	var total float64
+	for _, c := range candidates {
+		token := strings.ToLower(strings.TrimSpace(c.Token))
+		if len(token) == 0 {
+			continue
+		}
+		for _, label := range labels {
+			if strings.HasPrefix(strings.ToLower(label), token) {
+				p := math.Exp(c.LogProb)
+				out[label] += p
+				total += p
+				break
+			}
+		}
+	}
+	if total > 0 {
+		for k := range out {
+			out[k] /= total
+		}
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package openai
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func topLogProb(token string, p float64) TopLogProb {
+	return TopLogProb{Token: token, LogProb: math.Log(p)}
+}
+
+func TestClassify(t *testing.T) {
+	labels := []string{"positive", "negative", "neutral"}
+	for _, tt := range []struct {
+		name    string
+		content []TokenLogProb
+		want    map[string]float64
+	}{
+		{
+			"prefixes",
+			[]TokenLogProb{{
+				Token:   " Positive",
+				LogProb: math.Log(0.6),
+				TopLogProbs: []TopLogProb{
+					topLogProb(" Positive", 0.6),
+					topLogProb("neg", 0.2),
+					topLogProb("pos", 0.1),    // also positive
+					topLogProb("Hello", 0.05), // no label
+					topLogProb(" ", 0.05),     // blank
+				},
+			}},
+			map[string]float64{"positive": 0.7 / 0.9, "negative": 0.2 / 0.9},
+		},
+		{
+			"ambiguous prefix",
+			[]TokenLogProb{{Token: "ne", TopLogProbs: []TopLogProb{topLogProb("ne", 0.5), topLogProb("neu", 0.25)}}},
+			map[string]float64{"negative": 0.5 / 0.75, "neutral": 0.25 / 0.75}, // the first label it prefixes
+		},
+		{
+			"only the first token",
+			[]TokenLogProb{
+				{Token: "neutral", LogProb: math.Log(0.9), TopLogProbs: []TopLogProb{topLogProb("neutral", 0.9)}},
+				{Token: "positive", LogProb: math.Log(0.9), TopLogProbs: []TopLogProb{topLogProb("positive", 0.9)}},
+			},
+			map[string]float64{"neutral": 1},
+		},
+		{"no top logprobs", []TokenLogProb{{Token: "Neg", LogProb: math.Log(0.3)}}, map[string]float64{"negative": 1}},
+		{"no labels", []TokenLogProb{{Token: "maybe", TopLogProbs: []TopLogProb{topLogProb("maybe", 0.9), topLogProb("unsure", 0.1)}}}, map[string]float64{}},
+		{"no content", nil, map[string]float64{}},
+	} {
+		got := LogProbs{Content: tt.content}.Classify(labels...)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for k, p := range tt.want {
+			if math.Abs(got[k]-p) > 1e-12 {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLogProbs(t *testing.T) {
+	var l LogProbs
+	err := json.Unmarshal([]byte(`{"content":[
+		{"token":"The","logprob":-0.01,"bytes":[84,104,101],"top_logprobs":[{"token":"A","logprob":-5},{"token":"The","logprob":-0.01},{"token":"This","logprob":-4.6}]},
+		{"token":" sky","logprob":-2.3,"top_logprobs":[]},
+		{"token":" is","logprob":0}
+	]}`), &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Content[0].Alternatives(); !reflect.DeepEqual(got, []TopLogProb{{Token: "This", LogProb: -4.6}, {Token: "A", LogProb: -5}}) {
+		t.Errorf("alternatives %v", got)
+	}
+	if got := l.Content[1].Alternatives(); got != nil {
+		t.Errorf("alternatives %v", got)
+	}
+	if got, want := l.TotalLogProb(), -2.31; math.Abs(got-want) > 1e-12 {
+		t.Errorf("total %v", got)
+	}
+	if got, want := l.Perplexity(), math.Exp(2.31/3); math.Abs(got-want) > 1e-12 {
+		t.Errorf("perplexity %v", got)
+	}
+	if got := (LogProbs{}).Perplexity(); !math.IsNaN(got) {
+		t.Errorf("empty perplexity %v", got)
+	}
+	c := l.Confidences()
+	if len(c) != 3 || math.Abs(c[0]-math.Exp(-0.01)) > 1e-12 || math.Abs(c[1]-math.Exp(-2.3)) > 1e-12 || c[2] != 1 {
+		t.Errorf("confidences %v", c)
+	}
+	for threshold, want := range map[float64][]string{0.05: nil, 0.5: {" sky"}, 1: {"The", " sky"}, 1.1: {"The", " sky", " is"}} {
+		var got []string
+		for _, x := range l.LowConfidence(threshold) {
+			got = append(got, x.Token)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("below %v: %q", threshold, got)
+		}
+	}
+}
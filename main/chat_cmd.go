@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -32,9 +33,17 @@ func run() error {
 	if err != nil {
 		return err
 	}
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "subtitles":
+			return subtitles(c, os.Args[2:])
+		case "sessions":
+			return sessions(os.Args[2:])
+		}
+	}
 	var prompts []string
 	prompts = append(prompts, "remember, don't repeat a command's output if you've already echoed it to the user's terminal with EchoStdoutToChatStream=true.")
-	prompts = append(prompts, "you have a limited context window of 128k tokens, so make sure not to come close to exceeding that. for good measure, maybe limit output of all commands to 10k bytes, just in case.")
+	prompts = append(prompts, "limit the output of commands to about 10k bytes, so long outputs don't crowd out the conversation.")
 	prompts = append(prompts, `
 	
 	You are Open Interpreter, a world-class programmer that can complete any goal by executing code.
@@ -50,7 +59,19 @@ func run() error {
   
   `)
 
-	for _, prompt := range os.Args[1:] {
+	speak := flag.String("speak", "", "directory to save spoken assistant replies into")
+	voice := flag.String("voice", "alloy", "voice for spoken replies")
+	responses := flag.Bool("responses", false, "use the responses api, chaining turns server-side")
+	maxCost := flag.Float64("budget", 0, "dollars to stop the session at; 0 for no limit")
+	costs := flag.String("costs", "", "file to save a json cost report into")
+	window := flag.Int("context", 0, "prompt tokens to keep the history within, trimming and dropping older messages; 0 for no limit")
+	summarize := flag.Bool("summarize", false, "with -context, summarize older messages with gpt-4o-mini before dropping them")
+	resume := flag.String("resume", "", "id, or unique id prefix, of a saved session to continue")
+	save := flag.Bool("save", true, "save the session after every round, for resuming")
+	corpus := flag.String("corpus", "", "directory of text and markdown files the assistant can search")
+	flag.Parse()
+
+	for _, prompt := range flag.Args() {
 		fmt.Printf("adding prompt %s\n", prompt)
 		buf, err := os.ReadFile(prompt)
 		if err != nil {
@@ -58,5 +79,71 @@ func run() error {
 		}
 		prompts = append(prompts, string(buf))
 	}
-	return openai.Chat(c, prompts...)
+	o := openai.StandardChatOptions(prompts...)
+	o.Catalog = openai.DefaultCatalog()
+	if len(*corpus) > 0 {
+		x, err := openai.LoadCorpusDir(*corpus, openai.CorpusOptions{})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("indexed %d documents from %s\n", x.Len(), *corpus)
+		o.Functions = append(o.Functions, openai.NewCorpusSearch(x))
+	}
+	if len(*speak) > 0 {
+		o.Speech = &openai.SpeechOptions{
+			Dir: *speak,
+			Request: openai.SpeechRequest{
+				Voice: *voice,
+			},
+		}
+	}
+	store, err := openai.DefaultSessionStore()
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(*resume) > 0:
+		s, err := store.Load(*resume)
+		if err != nil {
+			return err
+		}
+		s.Resume(&o)
+		fmt.Printf("resuming session %s: %s\n", s.ID, s.Title)
+		for _, m := range o.Messages {
+			if m.Role == "user" || m.Role == "assistant" && len(m.Content) > 0 {
+				fmt.Printf("%s: %s\n", m.Role, m.Text())
+			}
+		}
+	case *save:
+		s, err := store.New(o)
+		if err != nil {
+			return err
+		}
+		o.Session = s
+		fmt.Printf("session %s\n", s.ID)
+	}
+	if o.Tree == nil {
+		o.Tree = openai.NewConversation(o.Messages...)
+	}
+	if *responses {
+		o.Responses = &openai.ResponsesOptions{Chain: true}
+	}
+	if *window > 0 {
+		o.History = openai.ContextLimit(*window, *summarize)
+	}
+	c.Costs = &openai.CostTracker{
+		Catalog: o.Catalog,
+		MaxCost: *maxCost,
+		OnRecord: func(r openai.RequestCost) {
+			fmt.Fprintf(os.Stderr, "\n(%s: %v)\n", r.Label, r)
+		},
+	}
+	err = openai.ChatAndPrint(c, &o)
+	fmt.Fprintf(os.Stderr, "\n%v\n", c.Costs.Report())
+	if len(*costs) > 0 {
+		if err := c.Costs.SaveJSON(*costs); err != nil {
+			return err
+		}
+	}
+	return err
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/xoba/openai"
+)
+
+// lists, searches, shows, or deletes saved chat sessions
+func sessions(args []string) error {
+	fs := flag.NewFlagSet("sessions", flag.ExitOnError)
+	search := fs.String("search", "", "only list sessions containing this text")
+	show := fs.String("show", "", "id of a session whose transcript to print")
+	del := fs.String("delete", "", "id of a session to delete")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s sessions [flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	store, err := openai.DefaultSessionStore()
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(*show) > 0:
+		s, err := store.Load(*show)
+		if err != nil {
+			return err
+		}
+		fmt.Println(s)
+		for _, m := range s.Messages {
+			fmt.Printf("%s: %s\n", m.Role, m.Text())
+		}
+		return nil
+	case len(*del) > 0:
+		s, err := store.Load(*del)
+		if err != nil {
+			return err
+		}
+		return store.Delete(s.ID)
+	}
+	var list []*openai.Session
+	if len(*search) > 0 {
+		list, err = store.Search(*search)
+	} else {
+		list, err = store.List()
+	}
+	if err != nil {
+		if list == nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "skipped: %v\n", err)
+	}
+	for _, s := range list {
+		fmt.Println(s)
+	}
+	return nil
+}
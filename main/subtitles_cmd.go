@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xoba/openai"
+)
+
+// transcribes an audio file into an srt or vtt subtitle file
+func subtitles(c *openai.Client, args []string) error {
+	fs := flag.NewFlagSet("subtitles", flag.ExitOnError)
+	model := fs.String("model", "whisper-1", "transcription model")
+	language := fs.String("language", "", "iso-639-1 language of the audio, if known")
+	translate := fs.String("translate", "", "language to translate the subtitles into")
+	chatModel := fs.String("chat-model", "gpt-4o-mini", "model for translating subtitles")
+	maxChars := fs.Int("max-chars", 42, "max characters per line")
+	maxLines := fs.Int("max-lines", 2, "max lines per cue")
+	shift := fs.Duration("shift", 0, "shift all cues by this much")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s subtitles [flags] <audio file> <output.srt|output.vtt>\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("need an audio file and an output file")
+	}
+	in, out := fs.Arg(0), fs.Arg(1)
+	format := strings.TrimPrefix(filepath.Ext(out), ".")
+	// check the format before paying for the transcription
+	if _, err := (openai.Subtitles{}).Format(format); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	t, err := c.Transcribe(ctx, in, openai.TranscriptionRequest{
+		Model:                  *model,
+		Language:               *language,
+		ResponseFormat:         "verbose_json",
+		TimestampGranularities: []string{"segment"},
+	})
+	if err != nil {
+		return err
+	}
+	s := openai.TranscriptionSubtitles(t, openai.SegmentOptions{
+		MaxChars: *maxChars,
+		MaxLines: *maxLines,
+	}).Shift(*shift)
+	if len(*translate) > 0 {
+		if s, err = c.TranslateSubtitles(ctx, s, *translate, *chatModel); err != nil {
+			return err
+		}
+	}
+	text, err := s.Format(format)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, []byte(text), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d cues to %s\n", len(s), out)
+	return nil
+}
@@ -0,0 +1,250 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type ModerationRequest struct {
+	Model string            `json:"model,omitempty"` // defaults to omni-moderation-latest
+	Input []ModerationInput `json:"input"`
+}
+
+// text or an image; images need an omni moderation model
+type ModerationInput struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+func TextModerationInput(text string) ModerationInput {
+	return ModerationInput{
+		Type: "text",
+		Text: text,
+	}
+}
+
+func ImageModerationInput(url string) ModerationInput {
+	return ModerationInput{
+		Type:     "image_url",
+		ImageURL: &ImageURL{URL: url},
+	}
+}
+
+type ModerationResponse struct {
+	ID      string             `json:"id"`
+	Model   string             `json:"model"`
+	Results []ModerationResult `json:"results"`
+}
+
+type ModerationResult struct {
+	Flagged                   bool                 `json:"flagged"`
+	Categories                ModerationCategories `json:"categories"`
+	CategoryScores            ModerationScores     `json:"category_scores"`
+	CategoryAppliedInputTypes map[string][]string  `json:"category_applied_input_types,omitempty"`
+}
+
+type ModerationCategories struct {
+	Harassment            bool `json:"harassment"`
+	HarassmentThreatening bool `json:"harassment/threatening"`
+	Hate                  bool `json:"hate"`
+	HateThreatening       bool `json:"hate/threatening"`
+	Illicit               bool `json:"illicit"`
+	IllicitViolent        bool `json:"illicit/violent"`
+	SelfHarm              bool `json:"self-harm"`
+	SelfHarmIntent        bool `json:"self-harm/intent"`
+	SelfHarmInstructions  bool `json:"self-harm/instructions"`
+	Sexual                bool `json:"sexual"`
+	SexualMinors          bool `json:"sexual/minors"`
+	Violence              bool `json:"violence"`
+	ViolenceGraphic       bool `json:"violence/graphic"`
+}
+
+type ModerationScores struct {
+	Harassment            float64 `json:"harassment"`
+	HarassmentThreatening float64 `json:"harassment/threatening"`
+	Hate                  float64 `json:"hate"`
+	HateThreatening       float64 `json:"hate/threatening"`
+	Illicit               float64 `json:"illicit"`
+	IllicitViolent        float64 `json:"illicit/violent"`
+	SelfHarm              float64 `json:"self-harm"`
+	SelfHarmIntent        float64 `json:"self-harm/intent"`
+	SelfHarmInstructions  float64 `json:"self-harm/instructions"`
+	Sexual                float64 `json:"sexual"`
+	SexualMinors          float64 `json:"sexual/minors"`
+	Violence              float64 `json:"violence"`
+	ViolenceGraphic       float64 `json:"violence/graphic"`
+}
+
+func (r ModerationResponse) String() string {
+	return toString(r)
+}
+
+// category names to scores, keyed as in the api
+func (s ModerationScores) Map() map[string]float64 {
+	buf, _ := json.Marshal(s)
+	var m map[string]float64
+	json.Unmarshal(buf, &m)
+	return m
+}
+
+// names of the flagged categories, sorted
+func (c ModerationCategories) Flagged() []string {
+	buf, _ := json.Marshal(c)
+	var m map[string]bool
+	json.Unmarshal(buf, &m)
+	var out []string
+	for k, v := range m {
+		if v {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (c *Client) Moderate(ctx context.Context, r ModerationRequest) (*ModerationResponse, error) {
+	if len(r.Model) == 0 {
+		r.Model = "omni-moderation-latest"
+	}
+	var out ModerationResponse
+	if err := c.PostContext(ctx, "moderations", r, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Results) == 0 {
+		return nil, fmt.Errorf("no moderation results")
+	}
+	return &out, nil
+}
+
+func (c *Client) ModerateText(ctx context.Context, text string) (*ModerationResult, error) {
+	r, err := c.Moderate(ctx, ModerationRequest{
+		Input: []ModerationInput{TextModerationInput(text)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r.Results[0], nil
+}
+
+// moderation stages in ChatWithOptions
+const (
+	StageUserInput  = "user input"
+	StageToolOutput = "tool output"
+	StageAssistant  = "assistant output"
+)
+
+// moderation actions
+const (
+	ModerationBlock  = "block"
+	ModerationRedact = "redact"
+	ModerationWarn   = "warn"
+)
+
+// configures moderation of chat messages in ChatWithOptions
+type ModerationPolicy struct {
+	Model      string             // defaults to omni-moderation-latest
+	Action     string             // block (default), redact, or warn
+	UserInput  bool               // moderate user messages before they're sent
+	ToolOutput bool               // moderate tool results before they're sent
+	Assistant  bool               // moderate assistant replies before they're shown
+	Thresholds map[string]float64 // optional per-category score limits, beyond the api's own flags
+	Redaction  string             // replacement text, defaults to "[removed by moderation]"
+}
+
+// returned when the policy blocks a message
+type ModerationError struct {
+	Stage      string
+	Categories []string
+}
+
+func (e *ModerationError) Error() string {
+	return fmt.Sprintf("%s blocked by moderation: %s", e.Stage, strings.Join(e.Categories, ", "))
+}
+
+func (p *ModerationPolicy) applies(stage string) bool {
+	switch stage {
+	case StageUserInput:
+		return p.UserInput
+	case StageToolOutput:
+		return p.ToolOutput
+	case StageAssistant:
+		return p.Assistant
+	}
+	return false
+}
+
+// moderates the message if the policy covers the stage, then blocks, redacts
+// it in place, or warns on stderr
+func (p *ModerationPolicy) check(ctx context.Context, c *Client, stage string, m *Message) error {
+	if p == nil || !p.applies(stage) {
+		return nil
+	}
+	var inputs []ModerationInput
+	if len(m.Content) > 0 {
+		inputs = append(inputs, TextModerationInput(m.Content))
+	}
+	for _, x := range m.Parts {
+		switch x.Type {
+		case "text":
+			inputs = append(inputs, TextModerationInput(x.Text))
+		case "image_url":
+			if x.ImageURL != nil {
+				inputs = append(inputs, ImageModerationInput(x.ImageURL.URL))
+			}
+		}
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+	r, err := c.Moderate(ctx, ModerationRequest{
+		Model: p.Model,
+		Input: inputs,
+	})
+	if err != nil {
+		return fmt.Errorf("can't moderate %s: %w", stage, err)
+	}
+	categories := make(map[string]bool)
+	for _, x := range r.Results {
+		for _, k := range x.Categories.Flagged() {
+			categories[k] = true
+		}
+		scores := x.CategoryScores.Map()
+		for k, limit := range p.Thresholds {
+			if scores[k] > limit {
+				categories[k] = true
+			}
+		}
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+	var list []string
+	for k := range categories {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	switch p.Action {
+	case ModerationRedact:
+		redaction := p.Redaction
+		if len(redaction) == 0 {
+			redaction = "[removed by moderation]"
+		}
+		m.Content = redaction
+		m.Parts = nil
+		fmt.Fprintf(os.Stderr, "moderation: redacted %s (%s)\n", stage, strings.Join(list, ", "))
+		return nil
+	case ModerationWarn:
+		fmt.Fprintf(os.Stderr, "moderation warning: %s flagged for %s\n", stage, strings.Join(list, ", "))
+		return nil
+	default:
+		return &ModerationError{
+			Stage:      stage,
+			Categories: list,
+		}
+	}
+}
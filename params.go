@@ -0,0 +1,89 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// optional chat completion parameters; pointers distinguish unset from zero
+type ChatParameters struct {
+	MaxTokens           int               `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int               `json:"max_completion_tokens,omitempty"` // replaces max_tokens for reasoning models
+	Temperature         *float64          `json:"temperature,omitempty"`
+	TopP                *float64          `json:"top_p,omitempty"`
+	N                   *int              `json:"n,omitempty"`
+	Stop                []string          `json:"stop,omitempty"`
+	Seed                *int              `json:"seed,omitempty"`
+	PresencePenalty     *float64          `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float64          `json:"frequency_penalty,omitempty"`
+	LogitBias           map[int]int       `json:"logit_bias,omitempty"` // token id to bias in [-100,100]
+	LogProbs            bool              `json:"logprobs,omitempty"`
+	TopLogProbs         *int              `json:"top_logprobs,omitempty"` // 0 to 20, requires LogProbs
+	User                string            `json:"user,omitempty"`
+	ToolChoice          *ToolChoice       `json:"tool_choice,omitempty"`
+	ParallelToolCalls   *bool             `json:"parallel_tool_calls,omitempty"`
+	Metadata            map[string]string `json:"metadata,omitempty"`
+	Store               *bool             `json:"store,omitempty"`
+	ServiceTier         string            `json:"service_tier,omitempty"` // auto, default, flex, or priority
+}
+
+// an error unless N asks for at most one choice, which is all streaming and
+// chat rounds handle
+func (p ChatParameters) singleChoice() error {
+	if p.N != nil && *p.N > 1 {
+		return fmt.Errorf("only one choice per request is supported, not n=%d", *p.N)
+	}
+	return nil
+}
+
+// pointer to v, for setting optional parameters
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// either a mode (auto, none, or required), or a named function the model must call
+type ToolChoice struct {
+	Mode     string
+	Function string
+}
+
+var (
+	ToolChoiceAuto     = &ToolChoice{Mode: "auto"}
+	ToolChoiceNone     = &ToolChoice{Mode: "none"}
+	ToolChoiceRequired = &ToolChoice{Mode: "required"}
+)
+
+// forces the model to call the named function
+func ToolChoiceFunction(name string) *ToolChoice {
+	return &ToolChoice{Function: name}
+}
+
+type namedToolChoice struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+func (t ToolChoice) MarshalJSON() ([]byte, error) {
+	if len(t.Function) == 0 {
+		return json.Marshal(t.Mode)
+	}
+	var n namedToolChoice
+	n.Type = "function"
+	n.Function.Name = t.Function
+	return json.Marshal(n)
+}
+
+func (t *ToolChoice) UnmarshalJSON(buf []byte) error {
+	*t = ToolChoice{}
+	if err := json.Unmarshal(buf, &t.Mode); err == nil {
+		return nil
+	}
+	var n namedToolChoice
+	if err := json.Unmarshal(buf, &n); err != nil {
+		return fmt.Errorf("bad tool choice: %s", buf)
+	}
+	t.Function = n.Function.Name
+	return nil
+}
@@ -0,0 +1,165 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToolChoiceJSON(t *testing.T) {
+	for _, tt := range []struct {
+		choice *ToolChoice
+		json   string
+	}{
+		{ToolChoiceNone, `"none"`},
+		{ToolChoiceAuto, `"auto"`},
+		{ToolChoiceRequired, `"required"`},
+		{ToolChoiceFunction("SquareRoot"), `{"type":"function","function":{"name":"SquareRoot"}}`},
+	} {
+		buf, err := json.Marshal(tt.choice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != tt.json {
+			t.Errorf("got %s, want %s", buf, tt.json)
+		}
+		var c ToolChoice
+		if err := json.Unmarshal(buf, &c); err != nil {
+			t.Fatal(err)
+		}
+		if c != *tt.choice {
+			t.Errorf("%s: round trip gave %+v", buf, c)
+		}
+	}
+	var c ToolChoice
+	if err := json.Unmarshal([]byte(`42`), &c); err == nil {
+		t.Error("decoded a number")
+	}
+}
+
+func TestChatParametersJSON(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		r    ChatRequest
+		json string // after the model and messages
+	}{
+		{"unset", ChatRequest{}, `}`},
+		{
+			"explicit zeros",
+			ChatRequest{ChatParameters: ChatParameters{
+				Temperature:       Ptr(0.0),
+				TopP:              Ptr(0.0),
+				N:                 Ptr(0),
+				Seed:              Ptr(0),
+				PresencePenalty:   Ptr(0.0),
+				FrequencyPenalty:  Ptr(0.0),
+				TopLogProbs:       Ptr(0),
+				ParallelToolCalls: Ptr(false),
+				Store:             Ptr(false),
+			}},
+			`,"temperature":0,"top_p":0,"n":0,"seed":0,"presence_penalty":0,"frequency_penalty":0,"top_logprobs":0,"parallel_tool_calls":false,"store":false}`,
+		},
+		{
+			"set",
+			ChatRequest{ChatParameters: ChatParameters{
+				MaxCompletionTokens: 100,
+				Temperature:         Ptr(0.5),
+				Stop:                []string{"\n"},
+				LogitBias:           map[int]int{50256: -100},
+				LogProbs:            true,
+				ToolChoice:          ToolChoiceRequired,
+				ServiceTier:         "flex",
+			}},
+			`,"max_completion_tokens":100,"temperature":0.5,"stop":["\n"],"logit_bias":{"50256":-100},"logprobs":true,"tool_choice":"required","service_tier":"flex"}`,
+		},
+		{"request fields", ChatRequest{MaxTokens: 10, Temperature: 0.7}, `,"max_tokens":10,"temperature":0.7}`},
+		{"zero request temperature", ChatRequest{Temperature: 0}, `}`},
+		{
+			"parameters over request fields",
+			ChatRequest{MaxTokens: 10, Temperature: 0.7, ChatParameters: ChatParameters{MaxTokens: 20, Temperature: Ptr(0.0)}},
+			`,"max_tokens":20,"temperature":0}`,
+		},
+	} {
+		tt.r.Model = "gpt-4o"
+		tt.r.Messages = []Message{{Role: "user", Content: "hi"}}
+		buf, err := json.Marshal(tt.r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]` + tt.json
+		if string(buf) != want {
+			t.Errorf("%s: got %s\nwant %s", tt.name, buf, want)
+		}
+		var r ChatRequest
+		if err := json.Unmarshal(buf, &r); err != nil {
+			t.Fatal(err)
+		}
+		if p := tt.r.parameters(); !reflect.DeepEqual(r.ChatParameters, p) {
+			t.Errorf("%s: round trip gave %+v", tt.name, r.ChatParameters)
+		}
+	}
+}
+
+// a stand-in streaming the reply's words, recording the requests
+func streamingStandIn(t *testing.T, reply string, requests *[]ChatRequest) *Client {
+	return apiStandIn(t, map[string]http.HandlerFunc{
+		"/chat/completions": func(w http.ResponseWriter, r *http.Request) {
+			var in ChatRequest
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				t.Error(err)
+			}
+			*requests = append(*requests, in)
+			chunk := func(d Delta, finish string) {
+				buf, _ := json.Marshal(StreamingChatCompletionResponse{
+					ID:      "chatcmpl-1",
+					Object:  "chat.completion.chunk",
+					Model:   in.Model,
+					Choices: []StreamingChoice{{Delta: d, FinishReason: finish}},
+				})
+				fmt.Fprintf(w, "data: %s\n\n", buf)
+			}
+			chunk(Delta{Role: "assistant"}, "")
+			for _, word := range strings.SplitAfter(reply, " ") {
+				chunk(Delta{Content: word}, "")
+			}
+			chunk(Delta{}, "stop")
+			io.WriteString(w, "data: [DONE]\n\n")
+		},
+	})
+}
+
+func TestStreaming(t *testing.T) {
+	var requests []ChatRequest
+	c := streamingStandIn(t, "hello there, friend", &requests)
+	messages := []Message{{Role: "user", Content: "hi"}}
+	w := new(strings.Builder)
+	resp, err := c.Streaming("gpt-4o", messages, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "hello there, friend" || w.String() != resp.Content || resp.FinishReason != "stop" {
+		t.Errorf("got %+v, wrote %q", resp, w)
+	}
+	if _, err := c.StreamingWithParameters("gpt-4o", messages, ChatParameters{Seed: Ptr(1), Temperature: Ptr(0.0)}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("%d requests", len(requests))
+	}
+	if r := requests[0]; !r.Stream || r.ChatParameters.Temperature == nil || *r.ChatParameters.Temperature != 0.7 {
+		t.Errorf("streaming request: %+v", r)
+	}
+	if p := requests[1].ChatParameters; p.Temperature == nil || *p.Temperature != 0 || p.Seed == nil || *p.Seed != 1 {
+		t.Errorf("parameters: %+v", p)
+	}
+	if _, err := c.StreamingWithParameters("gpt-4o", messages, ChatParameters{N: Ptr(2)}, io.Discard); err == nil {
+		t.Error("streamed two choices")
+	}
+	if len(requests) != 2 {
+		t.Error("sent a request for two choices")
+	}
+}
@@ -2,12 +2,17 @@ package openai
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"strings"
 )
 
 func (c Client) Post(endpoint string, in, out any) error {
-	resp, err := c.DoJSONRequest("POST", endpoint, in, nil)
+	return c.PostContext(context.Background(), endpoint, in, out)
+}
+
+func (c Client) PostContext(ctx context.Context, endpoint string, in, out any) error {
+	resp, err := c.DoJSONRequestContext(ctx, "POST", endpoint, in, nil)
 	if err != nil {
 		return err
 	}
@@ -25,17 +30,20 @@ type StreamingChatCompletionResponse struct {
 	Created int               `json:"created"`
 	Model   string            `json:"model"`
 	Choices []StreamingChoice `json:"choices,omitempty"`
+	Usage   *Usage            `json:"usage,omitempty"` // on the last chunk, with stream_options.include_usage
 }
 
 type StreamingChoice struct {
-	Index        int    `json:"index,omitempty"`
-	Delta        Delta  `json:"delta,omitempty"`
-	FinishReason string `json:"finish_reason,omitempty"`
+	Index        int       `json:"index,omitempty"`
+	Delta        Delta     `json:"delta,omitempty"`
+	LogProbs     *LogProbs `json:"logprobs,omitempty"`
+	FinishReason string    `json:"finish_reason,omitempty"`
 }
 
 type Delta struct {
 	Role      string     `json:"role,omitempty"`
 	Content   string     `json:"content,omitempty"`
+	Refusal   string     `json:"refusal,omitempty"`
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 }
 
@@ -0,0 +1,482 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type RealtimeOptions struct {
+	URL   string // defaults to wss://api.openai.com/v1/realtime, or a RealtimeStandIn's URL
+	Model string // defaults to gpt-realtime
+}
+
+type RealtimeSessionConfig struct {
+	Type             string               `json:"type,omitempty"` // realtime or transcription; defaults to realtime
+	Model            string               `json:"model,omitempty"`
+	Instructions     string               `json:"instructions,omitempty"`
+	OutputModalities []string             `json:"output_modalities,omitempty"` // text or audio
+	Audio            *RealtimeAudioConfig `json:"audio,omitempty"`
+	Tools            []ResponseTool       `json:"tools,omitempty"`
+	ToolChoice       string               `json:"tool_choice,omitempty"`
+	MaxOutputTokens  any                  `json:"max_output_tokens,omitempty"` // a number or "inf"
+}
+
+type RealtimeAudioConfig struct {
+	Input  *RealtimeAudioInput  `json:"input,omitempty"`
+	Output *RealtimeAudioOutput `json:"output,omitempty"`
+}
+
+type RealtimeAudioInput struct {
+	Format        *RealtimeAudioFormat `json:"format,omitempty"`
+	Transcription *struct {
+		Model    string `json:"model"`
+		Language string `json:"language,omitempty"`
+	} `json:"transcription,omitempty"`
+	TurnDetection *TurnDetection `json:"turn_detection,omitempty"`
+}
+
+type RealtimeAudioOutput struct {
+	Format *RealtimeAudioFormat `json:"format,omitempty"`
+	Voice  string               `json:"voice,omitempty"`
+	Speed  *float64             `json:"speed,omitempty"`
+}
+
+type RealtimeAudioFormat struct {
+	Type string `json:"type"`           // audio/pcm, audio/pcmu, or audio/pcma
+	Rate int    `json:"rate,omitempty"` // 24000 for pcm
+}
+
+// voice activity detection, which commits audio and creates responses on its own
+type TurnDetection struct {
+	Type              string   `json:"type"` // server_vad or semantic_vad
+	Threshold         *float64 `json:"threshold,omitempty"`
+	PrefixPaddingMs   int      `json:"prefix_padding_ms,omitempty"`
+	SilenceDurationMs int      `json:"silence_duration_ms,omitempty"`
+	CreateResponse    *bool    `json:"create_response,omitempty"`
+	InterruptResponse *bool    `json:"interrupt_response,omitempty"`
+}
+
+// a conversation item: a message, a function call, or a function call's output
+type RealtimeItem struct {
+	ID        string            `json:"id,omitempty"`
+	Type      string            `json:"type"` // message, function_call, or function_call_output
+	Object    string            `json:"object,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	Role      string            `json:"role,omitempty"`
+	Content   []RealtimeContent `json:"content,omitempty"`
+	CallID    string            `json:"call_id,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Arguments string            `json:"arguments,omitempty"`
+	Output    string            `json:"output,omitempty"`
+}
+
+type RealtimeContent struct {
+	Type       string `json:"type"` // input_text, input_audio, output_text, or output_audio
+	Text       string `json:"text,omitempty"`
+	Audio      string `json:"audio,omitempty"` // base64
+	Transcript string `json:"transcript,omitempty"`
+}
+
+// the text of the item's content, or its transcripts
+func (i RealtimeItem) Text() string {
+	var out string
+	for _, c := range i.Content {
+		out += c.Text + c.Transcript
+	}
+	return out
+}
+
+// options for one response, overriding the session's
+type RealtimeResponseConfig struct {
+	OutputModalities []string          `json:"output_modalities,omitempty"`
+	Instructions     string            `json:"instructions,omitempty"`
+	Tools            []ResponseTool    `json:"tools,omitempty"`
+	ToolChoice       string            `json:"tool_choice,omitempty"`
+	Conversation     string            `json:"conversation,omitempty"` // auto, or none for out-of-band responses
+	Input            []RealtimeItem    `json:"input,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+type RealtimeResponse struct {
+	ID            string         `json:"id"`
+	Object        string         `json:"object"`
+	Status        string         `json:"status"` // completed, cancelled, failed, incomplete, or in_progress
+	StatusDetails any            `json:"status_details,omitempty"`
+	Output        []RealtimeItem `json:"output"`
+	Usage         *RealtimeUsage `json:"usage,omitempty"`
+}
+
+type RealtimeUsage struct {
+	TotalTokens       int `json:"total_tokens"`
+	InputTokens       int `json:"input_tokens"`
+	OutputTokens      int `json:"output_tokens"`
+	InputTokenDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+		TextTokens   int `json:"text_tokens"`
+		AudioTokens  int `json:"audio_tokens"`
+	} `json:"input_token_details"`
+	OutputTokenDetails struct {
+		TextTokens  int `json:"text_tokens"`
+		AudioTokens int `json:"audio_tokens"`
+	} `json:"output_token_details"`
+}
+
+func (r RealtimeResponse) String() string {
+	return toString(r)
+}
+
+// the concatenated text, or audio transcript, of the response's messages
+func (r RealtimeResponse) Text() string {
+	var out string
+	for _, x := range r.Output {
+		if x.Type == "message" {
+			out += x.Text()
+		}
+	}
+	return out
+}
+
+type RealtimeError struct {
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+	EventID string `json:"event_id,omitempty"`
+}
+
+func (e *RealtimeError) Error() string {
+	return fmt.Sprintf("realtime %s: %s", e.Type, e.Message)
+}
+
+// client event types
+const (
+	RealtimeSessionUpdate    = "session.update"
+	RealtimeAudioAppend      = "input_audio_buffer.append"
+	RealtimeAudioCommit      = "input_audio_buffer.commit"
+	RealtimeAudioClear       = "input_audio_buffer.clear"
+	RealtimeItemCreate       = "conversation.item.create"
+	RealtimeItemRetrieve     = "conversation.item.retrieve"
+	RealtimeItemTruncate     = "conversation.item.truncate"
+	RealtimeItemDelete       = "conversation.item.delete"
+	RealtimeResponseCreate   = "response.create"
+	RealtimeResponseCancel   = "response.cancel"
+	RealtimeOutputAudioClear = "output_audio_buffer.clear"
+)
+
+// server event types
+const (
+	RealtimeErrorEvent         = "error"
+	RealtimeSessionCreated     = "session.created"
+	RealtimeSessionUpdated     = "session.updated"
+	RealtimeItemAdded          = "conversation.item.added"
+	RealtimeItemDone           = "conversation.item.done"
+	RealtimeItemDeleted        = "conversation.item.deleted"
+	RealtimeItemTruncated      = "conversation.item.truncated"
+	RealtimeAudioCommitted     = "input_audio_buffer.committed"
+	RealtimeAudioCleared       = "input_audio_buffer.cleared"
+	RealtimeSpeechStarted      = "input_audio_buffer.speech_started"
+	RealtimeSpeechStopped      = "input_audio_buffer.speech_stopped"
+	RealtimeInputTranscription = "conversation.item.input_audio_transcription.completed"
+	RealtimeResponseCreated    = "response.created"
+	RealtimeResponseDone       = "response.done"
+	RealtimeOutputItemAdded    = "response.output_item.added"
+	RealtimeOutputItemDone     = "response.output_item.done"
+	RealtimeContentPartAdded   = "response.content_part.added"
+	RealtimeContentPartDone    = "response.content_part.done"
+	RealtimeTextDelta          = "response.output_text.delta"
+	RealtimeTextDone           = "response.output_text.done"
+	RealtimeAudioDelta         = "response.output_audio.delta"
+	RealtimeAudioDone          = "response.output_audio.done"
+	RealtimeTranscriptDelta    = "response.output_audio_transcript.delta"
+	RealtimeTranscriptDone     = "response.output_audio_transcript.done"
+	RealtimeArgumentsDelta     = "response.function_call_arguments.delta"
+	RealtimeArgumentsDone      = "response.function_call_arguments.done"
+	RealtimeRateLimitsUpdated  = "rate_limits.updated"
+)
+
+// an event sent to the server; which fields are set depends on Type
+type RealtimeClientEvent struct {
+	Type           string                  `json:"type"`
+	EventID        string                  `json:"event_id,omitempty"`
+	Session        *RealtimeSessionConfig  `json:"session,omitempty"`
+	Audio          string                  `json:"audio,omitempty"` // base64
+	Item           *RealtimeItem           `json:"item,omitempty"`
+	PreviousItemID string                  `json:"previous_item_id,omitempty"`
+	ItemID         string                  `json:"item_id,omitempty"`
+	ContentIndex   *int                    `json:"content_index,omitempty"`
+	AudioEndMs     *int                    `json:"audio_end_ms,omitempty"`
+	Response       *RealtimeResponseConfig `json:"response,omitempty"`
+}
+
+// an event from the server; which fields are set depends on Type
+type RealtimeServerEvent struct {
+	Type           string                 `json:"type"`
+	EventID        string                 `json:"event_id"`
+	Session        *RealtimeSessionConfig `json:"session,omitempty"`
+	Item           *RealtimeItem          `json:"item,omitempty"`
+	PreviousItemID string                 `json:"previous_item_id,omitempty"`
+	ItemID         string                 `json:"item_id,omitempty"`
+	ResponseID     string                 `json:"response_id,omitempty"`
+	OutputIndex    int                    `json:"output_index"`
+	ContentIndex   int                    `json:"content_index"`
+	Delta          string                 `json:"delta,omitempty"` // text, or base64 audio
+	Text           string                 `json:"text,omitempty"`
+	Transcript     string                 `json:"transcript,omitempty"`
+	CallID         string                 `json:"call_id,omitempty"`
+	Name           string                 `json:"name,omitempty"`
+	Arguments      string                 `json:"arguments,omitempty"`
+	AudioStartMs   int                    `json:"audio_start_ms,omitempty"`
+	AudioEndMs     int                    `json:"audio_end_ms,omitempty"`
+	Response       *RealtimeResponse      `json:"response,omitempty"`
+	Error          *RealtimeError         `json:"error,omitempty"`
+}
+
+// a realtime session over a websocket
+type Realtime struct {
+	conn  *websocket.Conn
+	write sync.Mutex
+	funcs map[string]FunctionI
+}
+
+func (c *Client) DialRealtime(ctx context.Context, o RealtimeOptions) (*Realtime, error) {
+	if len(o.URL) == 0 {
+		o.URL = "wss://api.openai.com/v1/realtime"
+	}
+	if len(o.Model) == 0 {
+		o.Model = "gpt-realtime"
+	}
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("model", o.Model)
+	u.RawQuery = q.Encode()
+	h := make(http.Header)
+	h.Set("Authorization", "Bearer "+c.secretKey)
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, u.String(), h)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("can't dial realtime: %s: %w", resp.Status, err)
+		}
+		return nil, fmt.Errorf("can't dial realtime: %w", err)
+	}
+	return &Realtime{
+		conn:  conn,
+		funcs: make(map[string]FunctionI),
+	}, nil
+}
+
+func (r *Realtime) Close() error {
+	return r.conn.Close()
+}
+
+func (r *Realtime) Send(e RealtimeClientEvent) error {
+	r.write.Lock()
+	defer r.write.Unlock()
+	return r.conn.WriteJSON(e)
+}
+
+// the next event from the server
+func (r *Realtime) Recv() (*RealtimeServerEvent, error) {
+	var e RealtimeServerEvent
+	if err := r.conn.ReadJSON(&e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// updates the session, adding function tools for funcs, which Await will run
+// when the model calls them
+func (r *Realtime) UpdateSession(s RealtimeSessionConfig, funcs ...FunctionI) error {
+	if len(s.Type) == 0 {
+		s.Type = "realtime"
+	}
+	for _, f := range funcs {
+		fd := functionDefinition(f)
+		r.funcs[fd.Name] = f
+		s.Tools = append(s.Tools, ResponseTool{
+			Type:        "function",
+			Name:        fd.Name,
+			Description: fd.Description,
+			Parameters:  fd.Parameters,
+		})
+	}
+	return r.Send(RealtimeClientEvent{Type: RealtimeSessionUpdate, Session: &s})
+}
+
+func (r *Realtime) AddItem(item RealtimeItem) error {
+	return r.Send(RealtimeClientEvent{Type: RealtimeItemCreate, Item: &item})
+}
+
+// adds a text message to the conversation
+func (r *Realtime) AddText(role, text string) error {
+	t := "input_text"
+	if role == "assistant" {
+		t = "output_text"
+	}
+	return r.AddItem(RealtimeItem{
+		Type:    "message",
+		Role:    role,
+		Content: []RealtimeContent{{Type: t, Text: text}},
+	})
+}
+
+func (r *Realtime) DeleteItem(id string) error {
+	return r.Send(RealtimeClientEvent{Type: RealtimeItemDelete, ItemID: id})
+}
+
+// cuts an assistant audio item off at the point playback stopped
+func (r *Realtime) TruncateItem(id string, contentIndex, audioEndMs int) error {
+	return r.Send(RealtimeClientEvent{
+		Type:         RealtimeItemTruncate,
+		ItemID:       id,
+		ContentIndex: &contentIndex,
+		AudioEndMs:   &audioEndMs,
+	})
+}
+
+// appends audio in the session's input format to the input buffer
+func (r *Realtime) AppendAudio(audio []byte) error {
+	return r.Send(RealtimeClientEvent{
+		Type:  RealtimeAudioAppend,
+		Audio: base64.StdEncoding.EncodeToString(audio),
+	})
+}
+
+// commits the input buffer as a user message
+func (r *Realtime) CommitAudio() error {
+	return r.Send(RealtimeClientEvent{Type: RealtimeAudioCommit})
+}
+
+func (r *Realtime) ClearAudio() error {
+	return r.Send(RealtimeClientEvent{Type: RealtimeAudioClear})
+}
+
+// asks for a response; c may be nil to use the session's settings
+func (r *Realtime) CreateResponse(c *RealtimeResponseConfig) error {
+	return r.Send(RealtimeClientEvent{Type: RealtimeResponseCreate, Response: c})
+}
+
+func (r *Realtime) CancelResponse() error {
+	return r.Send(RealtimeClientEvent{Type: RealtimeResponseCancel})
+}
+
+// callbacks for streamed output; any may be nil
+type RealtimeHandler struct {
+	Text       func(string)                     // output text deltas
+	Audio      func([]byte)                     // decoded output audio deltas
+	Transcript func(string)                     // output audio transcript deltas
+	Event      func(*RealtimeServerEvent) error // every event
+}
+
+// asks for a response and awaits it
+func (r *Realtime) Respond(ctx context.Context, h RealtimeHandler) (*RealtimeResponse, error) {
+	if err := r.CreateResponse(nil); err != nil {
+		return nil, err
+	}
+	return r.Await(ctx, h)
+}
+
+// reads events until a response is done, streaming its deltas to the handler.
+// if the response calls functions, they're run, their outputs added to the
+// conversation, and another response created, until one makes no calls.
+// cancelling the context interrupts the read, after which the websocket can't
+// be read again, so it also closes the session.
+func (r *Realtime) Await(ctx context.Context, h RealtimeHandler) (*RealtimeResponse, error) {
+	stop := context.AfterFunc(ctx, func() {
+		r.conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+	for {
+		e, err := r.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				r.conn.Close()
+				return nil, fmt.Errorf("realtime session closed: %w", ctx.Err())
+			}
+			return nil, err
+		}
+		if h.Event != nil {
+			if err := h.Event(e); err != nil {
+				return nil, err
+			}
+		}
+		switch e.Type {
+		case RealtimeErrorEvent:
+			if e.Error == nil {
+				return nil, fmt.Errorf("realtime error")
+			}
+			return nil, e.Error
+		case RealtimeTextDelta:
+			if h.Text != nil {
+				h.Text(e.Delta)
+			}
+		case RealtimeTranscriptDelta:
+			if h.Transcript != nil {
+				h.Transcript(e.Delta)
+			}
+		case RealtimeAudioDelta:
+			if h.Audio != nil {
+				buf, err := base64.StdEncoding.DecodeString(e.Delta)
+				if err != nil {
+					return nil, fmt.Errorf("bad audio delta: %w", err)
+				}
+				h.Audio(buf)
+			}
+		case RealtimeResponseDone:
+			if e.Response == nil {
+				return nil, fmt.Errorf("response.done without a response")
+			}
+			called, err := r.runCalls(e.Response)
+			if err != nil {
+				return nil, err
+			}
+			if !called {
+				return e.Response, nil
+			}
+			if err := r.CreateResponse(nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// runs the response's function calls and adds their outputs to the conversation
+func (r *Realtime) runCalls(resp *RealtimeResponse) (bool, error) {
+	var called bool
+	for _, x := range resp.Output {
+		if x.Type != "function_call" {
+			continue
+		}
+		f, ok := r.funcs[x.Name]
+		if !ok {
+			return false, fmt.Errorf("unknown func: %q", x.Name)
+		}
+		f.Clear()
+		if err := json.Unmarshal([]byte(x.Arguments), f); err != nil {
+			return false, fmt.Errorf("%w: can't parse arguments of %q --- %s", err, x.Name, x.Arguments)
+		}
+		out, err := f.Run()
+		if err != nil {
+			return false, fmt.Errorf("can't run %q: %w", x.Name, err)
+		}
+		if err := r.AddItem(RealtimeItem{
+			Type:   "function_call_output",
+			CallID: x.CallID,
+			Output: out,
+		}); err != nil {
+			return false, err
+		}
+		called = true
+	}
+	return called, nil
+}
@@ -0,0 +1,325 @@
+package openai
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// a local stand-in for the realtime api, for exercising realtime clients
+// without the network or a key. it keeps each connection's session and
+// conversation, acknowledges client events the way the api does, and answers
+// response.create with Reply, streaming text or audio deltas.
+type RealtimeStandIn struct {
+	URL string // to dial, via RealtimeOptions
+
+	// makes the reply item for a response; defaults to StandInReply
+	Reply func(session RealtimeSessionConfig, conversation []RealtimeItem) RealtimeItem
+
+	listener net.Listener
+	server   *http.Server
+}
+
+// starts a stand-in on a local port
+func NewRealtimeStandIn() (*RealtimeStandIn, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &RealtimeStandIn{
+		URL:      fmt.Sprintf("ws://%s/v1/realtime", l.Addr()),
+		Reply:    StandInReply,
+		listener: l,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/realtime", s.serve)
+	s.server = &http.Server{Handler: mux}
+	go s.server.Serve(l)
+	return s, nil
+}
+
+func (s *RealtimeStandIn) Close() error {
+	return s.server.Close()
+}
+
+// the default reply: a call to any session tool whose name the last user message
+// mentions, a summary of the last function output, or else an echo
+func StandInReply(session RealtimeSessionConfig, conversation []RealtimeItem) RealtimeItem {
+	text := func(s string) RealtimeItem {
+		return RealtimeItem{
+			Type:    "message",
+			Role:    "assistant",
+			Content: []RealtimeContent{{Type: "output_text", Text: s}},
+		}
+	}
+	if len(conversation) == 0 {
+		return text("hello")
+	}
+	last := conversation[len(conversation)-1]
+	switch {
+	case last.Type == "function_call_output":
+		return text("the result is " + last.Output)
+	case last.Type == "message" && last.Role == "user":
+		said := last.Text()
+		for _, t := range session.Tools {
+			if len(t.Name) > 0 && strings.Contains(strings.ToLower(said), strings.ToLower(t.Name)) {
+				return RealtimeItem{
+					Type:      "function_call",
+					Name:      t.Name,
+					Arguments: "{}",
+				}
+			}
+		}
+		return text("you said: " + said)
+	}
+	return text("ok")
+}
+
+var standInUpgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// one connection's state
+type standInConn struct {
+	conn         *websocket.Conn
+	write        sync.Mutex
+	reply        func(RealtimeSessionConfig, []RealtimeItem) RealtimeItem
+	session      RealtimeSessionConfig
+	conversation []RealtimeItem
+	audio        []byte
+	ids          int
+}
+
+func (s *RealtimeStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return
+	}
+	conn, err := standInUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	c := &standInConn{
+		conn:  conn,
+		reply: s.Reply,
+		session: RealtimeSessionConfig{
+			Type:             "realtime",
+			Model:            r.URL.Query().Get("model"),
+			OutputModalities: []string{"audio"},
+		},
+	}
+	c.send(RealtimeServerEvent{Type: RealtimeSessionCreated, Session: &c.session})
+	for {
+		var e RealtimeClientEvent
+		if err := conn.ReadJSON(&e); err != nil {
+			return
+		}
+		if err := c.handle(e); err != nil {
+			c.send(RealtimeServerEvent{
+				Type: RealtimeErrorEvent,
+				Error: &RealtimeError{
+					Type:    "invalid_request_error",
+					Message: err.Error(),
+					EventID: e.EventID,
+				},
+			})
+		}
+	}
+}
+
+func (c *standInConn) id(prefix string) string {
+	c.ids++
+	return fmt.Sprintf("%s_%06d", prefix, c.ids)
+}
+
+func (c *standInConn) send(e RealtimeServerEvent) {
+	c.write.Lock()
+	defer c.write.Unlock()
+	e.EventID = c.id("event")
+	c.conn.WriteJSON(e)
+}
+
+func (c *standInConn) handle(e RealtimeClientEvent) error {
+	switch e.Type {
+	case RealtimeSessionUpdate:
+		if e.Session == nil {
+			return fmt.Errorf("missing session")
+		}
+		// fields replace the session's only when given
+		buf, _ := json.Marshal(e.Session)
+		if err := json.Unmarshal(buf, &c.session); err != nil {
+			return err
+		}
+		c.send(RealtimeServerEvent{Type: RealtimeSessionUpdated, Session: &c.session})
+	case RealtimeItemCreate:
+		if e.Item == nil {
+			return fmt.Errorf("missing item")
+		}
+		c.add(*e.Item)
+	case RealtimeItemDelete:
+		for i, x := range c.conversation {
+			if x.ID == e.ItemID {
+				c.conversation = append(c.conversation[:i], c.conversation[i+1:]...)
+				c.send(RealtimeServerEvent{Type: RealtimeItemDeleted, ItemID: e.ItemID})
+				return nil
+			}
+		}
+		return fmt.Errorf("no item %q", e.ItemID)
+	case RealtimeItemTruncate:
+		for _, x := range c.conversation {
+			if x.ID == e.ItemID {
+				c.send(RealtimeServerEvent{Type: RealtimeItemTruncated, ItemID: e.ItemID})
+				return nil
+			}
+		}
+		return fmt.Errorf("no item %q", e.ItemID)
+	case RealtimeAudioAppend:
+		buf, err := base64.StdEncoding.DecodeString(e.Audio)
+		if err != nil {
+			return fmt.Errorf("bad audio: %w", err)
+		}
+		c.audio = append(c.audio, buf...)
+	case RealtimeAudioCommit:
+		if len(c.audio) == 0 {
+			return fmt.Errorf("input audio buffer is empty")
+		}
+		item := RealtimeItem{
+			Type: "message",
+			Role: "user",
+			Content: []RealtimeContent{{
+				Type:       "input_audio",
+				Transcript: fmt.Sprintf("(%d bytes of audio)", len(c.audio)),
+			}},
+		}
+		c.audio = nil
+		id := c.add(item)
+		c.send(RealtimeServerEvent{Type: RealtimeAudioCommitted, ItemID: id})
+	case RealtimeAudioClear:
+		c.audio = nil
+		c.send(RealtimeServerEvent{Type: RealtimeAudioCleared})
+	case RealtimeResponseCreate:
+		c.respond(e.Response)
+	case RealtimeResponseCancel, RealtimeOutputAudioClear:
+		// responses are sent whole, so there's nothing in flight to cancel
+	default:
+		return fmt.Errorf("unsupported event type: %q", e.Type)
+	}
+	return nil
+}
+
+// adds an item to the conversation, returning its id
+func (c *standInConn) add(item RealtimeItem) string {
+	if len(item.ID) == 0 {
+		item.ID = c.id("item")
+	}
+	item.Object = "realtime.item"
+	item.Status = "completed"
+	var previous string
+	if n := len(c.conversation); n > 0 {
+		previous = c.conversation[n-1].ID
+	}
+	c.conversation = append(c.conversation, item)
+	c.send(RealtimeServerEvent{Type: RealtimeItemAdded, PreviousItemID: previous, Item: &item})
+	c.send(RealtimeServerEvent{Type: RealtimeItemDone, PreviousItemID: previous, Item: &item})
+	return item.ID
+}
+
+func (c *standInConn) respond(config *RealtimeResponseConfig) {
+	session := c.session
+	if config != nil && len(config.OutputModalities) > 0 {
+		session.OutputModalities = config.OutputModalities
+	}
+	resp := RealtimeResponse{
+		ID:     c.id("resp"),
+		Object: "realtime.response",
+		Status: "in_progress",
+	}
+	c.send(RealtimeServerEvent{Type: RealtimeResponseCreated, Response: &resp})
+
+	item := c.reply(session, c.conversation)
+	item.ID = c.id("item")
+	item.Object = "realtime.item"
+	item.Status = "in_progress"
+	added := item
+	added.Content = nil
+	added.Arguments = ""
+	c.send(RealtimeServerEvent{Type: RealtimeOutputItemAdded, ResponseID: resp.ID, Item: &added})
+
+	ids := RealtimeServerEvent{ResponseID: resp.ID, ItemID: item.ID}
+	event := func(t string) RealtimeServerEvent {
+		e := ids
+		e.Type = t
+		return e
+	}
+	var words int
+	switch item.Type {
+	case "function_call":
+		if len(item.CallID) == 0 {
+			item.CallID = c.id("call")
+		}
+		e := event(RealtimeArgumentsDelta)
+		e.CallID = item.CallID
+		e.Delta = item.Arguments
+		c.send(e)
+		e = event(RealtimeArgumentsDone)
+		e.CallID = item.CallID
+		e.Name = item.Name
+		e.Arguments = item.Arguments
+		c.send(e)
+	case "message":
+		text := item.Text()
+		audio := len(session.OutputModalities) > 0 && session.OutputModalities[0] == "audio"
+		part := RealtimeContent{Type: "output_text"}
+		delta, done := RealtimeTextDelta, RealtimeTextDone
+		if audio {
+			part.Type = "output_audio"
+			delta, done = RealtimeTranscriptDelta, RealtimeTranscriptDone
+		}
+		c.send(event(RealtimeContentPartAdded))
+		for _, w := range strings.SplitAfter(text, " ") {
+			words++
+			e := event(delta)
+			e.Delta = w
+			c.send(e)
+			if audio {
+				// a tenth of a second of 24khz pcm16 silence per word
+				e := event(RealtimeAudioDelta)
+				e.Delta = base64.StdEncoding.EncodeToString(make([]byte, 4800))
+				c.send(e)
+			}
+		}
+		e := event(done)
+		if audio {
+			e.Transcript = text
+			c.send(event(RealtimeAudioDone))
+			part.Transcript = text
+		} else {
+			e.Text = text
+			part.Text = text
+		}
+		c.send(e)
+		c.send(event(RealtimeContentPartDone))
+		item.Content = []RealtimeContent{part}
+	}
+	item.Status = "completed"
+	c.send(RealtimeServerEvent{Type: RealtimeOutputItemDone, ResponseID: resp.ID, Item: &item})
+	if config == nil || config.Conversation != "none" {
+		c.add(item)
+	}
+
+	resp.Status = "completed"
+	resp.Output = []RealtimeItem{item}
+	resp.Usage = &RealtimeUsage{
+		InputTokens:  len(c.conversation),
+		OutputTokens: words + 1,
+	}
+	resp.Usage.TotalTokens = resp.Usage.InputTokens + resp.Usage.OutputTokens
+	c.send(RealtimeServerEvent{Type: RealtimeResponseDone, Response: &resp})
+}
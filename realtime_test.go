@@ -0,0 +1,171 @@
+package openai
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a session with a stand-in replying with reply, or its default if nil
+func standIn(t *testing.T, reply func(RealtimeSessionConfig, []RealtimeItem) RealtimeItem) *Realtime {
+	s, err := NewRealtimeStandIn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != nil {
+		s.Reply = reply
+	}
+	t.Cleanup(func() { s.Close() })
+	c, err := NewClient("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	r, err := c.DialRealtime(ctx, RealtimeOptions{URL: s.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	e, err := r.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != RealtimeSessionCreated {
+		t.Fatalf("first event: %s", e.Type)
+	}
+	return r
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestRealtimeTextDeltas(t *testing.T) {
+	r := standIn(t, nil)
+	if err := r.UpdateSession(RealtimeSessionConfig{OutputModalities: []string{"text"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddText("user", "hello there, stand-in"); err != nil {
+		t.Fatal(err)
+	}
+	var deltas []string
+	resp, err := r.Respond(testContext(t), RealtimeHandler{
+		Text: func(s string) { deltas = append(deltas, s) },
+		Transcript: func(string) {
+			t.Error("audio transcript for a text session")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "you said: hello there, stand-in"
+	if got := resp.Text(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if len(deltas) < 2 || strings.Join(deltas, "") != want {
+		t.Errorf("deltas: %q", deltas)
+	}
+	if resp.Status != "completed" || resp.Usage == nil {
+		t.Errorf("response: %v", resp)
+	}
+}
+
+func TestRealtimeAudio(t *testing.T) {
+	r := standIn(t, nil)
+	if err := r.AppendAudio(make([]byte, 960)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CommitAudio(); err != nil {
+		t.Fatal(err)
+	}
+	var audio int
+	var transcript string
+	resp, err := r.Respond(testContext(t), RealtimeHandler{
+		Audio:      func(b []byte) { audio += len(b) },
+		Transcript: func(s string) { transcript += s },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "you said: (960 bytes of audio)"
+	if transcript != want || resp.Text() != want {
+		t.Errorf("transcript %q, text %q; want %q", transcript, resp.Text(), want)
+	}
+	if audio == 0 {
+		t.Error("no audio")
+	}
+}
+
+func TestRealtimeFunctionCall(t *testing.T) {
+	r := standIn(t, func(session RealtimeSessionConfig, conversation []RealtimeItem) RealtimeItem {
+		item := StandInReply(session, conversation)
+		if item.Type == "function_call" {
+			item.Arguments = `{"Argument":16}`
+		}
+		return item
+	})
+	if err := r.UpdateSession(RealtimeSessionConfig{OutputModalities: []string{"text"}}, &SquareRoot{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddText("user", "what's the squareroot of 16?"); err != nil {
+		t.Fatal(err)
+	}
+	var tools []ResponseTool
+	var calls, outputs int
+	resp, err := r.Respond(testContext(t), RealtimeHandler{
+		Event: func(e *RealtimeServerEvent) error {
+			switch {
+			case e.Type == RealtimeSessionUpdated:
+				tools = e.Session.Tools
+			case e.Type == RealtimeArgumentsDone:
+				calls++
+				if e.Name != "SquareRoot" || len(e.CallID) == 0 {
+					t.Errorf("call: %s %q", e.Name, e.CallID)
+				}
+			case e.Type == RealtimeItemAdded && e.Item.Type == "function_call_output":
+				outputs++
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 1 || tools[0].Name != "SquareRoot" || tools[0].Type != "function" || tools[0].Parameters == nil {
+		t.Errorf("session tools: %v", tools)
+	}
+	if calls != 1 || outputs != 1 {
+		t.Errorf("%d calls, %d outputs", calls, outputs)
+	}
+	if got, want := resp.Text(), "the result is 4.000000"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRealtimeError(t *testing.T) {
+	r := standIn(t, nil)
+	if err := r.DeleteItem("nonesuch"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := r.Await(testContext(t), RealtimeHandler{})
+	if _, ok := err.(*RealtimeError); !ok {
+		t.Errorf("got %v, want a realtime error", err)
+	}
+}
+
+func TestRealtimeCancel(t *testing.T) {
+	r := standIn(t, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	// no response was asked for, so this waits until the deadline
+	if _, err := r.Await(ctx, RealtimeHandler{}); err == nil || ctx.Err() == nil {
+		t.Fatalf("got %v before the deadline", err)
+	}
+	if err := r.AddText("user", "hello"); err == nil {
+		t.Error("session still open after cancellation")
+	}
+}
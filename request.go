@@ -1,9 +1,14 @@
 package openai
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"os"
 	"path"
@@ -12,21 +17,96 @@ import (
 )
 
 func (c Client) DoJSONRequest(method, endpoint string, in any, headers map[string]string) (*http.Response, error) {
-	return c.DoRequest("POST", endpoint, in, map[string]string{
+	return c.DoJSONRequestContext(context.Background(), method, endpoint, in, headers)
+}
+
+func (c Client) DoJSONRequestContext(ctx context.Context, method, endpoint string, in any, headers map[string]string) (*http.Response, error) {
+	h := map[string]string{
 		"Content-Type": "application/json",
-	})
+	}
+	for k, v := range headers {
+		h[k] = v
+	}
+	return c.DoRequestContext(ctx, method, endpoint, in, h)
 }
 
 func (c Client) DoRequest(method, endpoint string, in any, headers map[string]string) (*http.Response, error) {
-	var u url.URL
-	u.Scheme = "https"
-	u.Host = "api.openai.com"
-	u.Path = path.Join("v1", endpoint)
+	return c.DoRequestContext(context.Background(), method, endpoint, in, headers)
+}
+
+func (c Client) DoRequestContext(ctx context.Context, method, endpoint string, in any, headers map[string]string) (*http.Response, error) {
 	var body io.Reader
 	if in != nil {
 		body = strings.NewReader(toString(in))
 	}
-	req, err := http.NewRequest(method, u.String(), body)
+	return c.doRequest(ctx, method, endpoint, body, headers)
+}
+
+// a file part of a multipart request
+type FormFile struct {
+	Field       string // form field name, e.g. "file"
+	Filename    string
+	ContentType string // defaults to application/octet-stream
+	Reader      io.Reader
+}
+
+// sends fields and files as multipart/form-data, streaming the body
+func (c Client) DoMultipartRequest(ctx context.Context, method, endpoint string, fields url.Values, files []FormFile) (*http.Response, error) {
+	r, w := io.Pipe()
+	mw := multipart.NewWriter(w)
+	go func() {
+		w.CloseWithError(func() error {
+			for k, list := range fields {
+				for _, v := range list {
+					if err := mw.WriteField(k, v); err != nil {
+						return err
+					}
+				}
+			}
+			for _, f := range files {
+				h := make(textproto.MIMEHeader)
+				h.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, f.Field, f.Filename))
+				contentType := f.ContentType
+				if len(contentType) == 0 {
+					contentType = "application/octet-stream"
+				}
+				h.Set("Content-Type", contentType)
+				part, err := mw.CreatePart(h)
+				if err != nil {
+					return err
+				}
+				if _, err := io.Copy(part, f.Reader); err != nil {
+					return err
+				}
+			}
+			return mw.Close()
+		}())
+	}()
+	resp, err := c.doRequest(ctx, method, endpoint, r, map[string]string{
+		"Content-Type": mw.FormDataContentType(),
+	})
+	r.Close()
+	return resp, err
+}
+
+// posts a multipart request and decodes the json response
+func (c Client) PostMultipart(ctx context.Context, endpoint string, fields url.Values, files []FormFile, out any) error {
+	resp, err := c.DoMultipartRequest(ctx, "POST", endpoint, fields, files)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c Client) doRequest(ctx context.Context, method, endpoint string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	u, err := url.Parse(cmp.Or(c.BaseURL, "https://api.openai.com/v1"))
+	if err != nil {
+		return nil, err
+	}
+	endpoint, u.RawQuery, _ = strings.Cut(endpoint, "?")
+	u.Path = path.Join(u.Path, endpoint)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,65 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// a client of a local server standing in for the api, with handlers for
+// endpoints like /chat/completions
+func apiStandIn(t *testing.T, handlers map[string]http.HandlerFunc) *Client {
+	mux := http.NewServeMux()
+	for endpoint, h := range handlers {
+		mux.HandleFunc("/v1"+endpoint, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer test" {
+				t.Errorf("%s: authorization %q", endpoint, r.Header.Get("Authorization"))
+			}
+			h(w, r)
+		})
+	}
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	c, err := NewClient("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.BaseURL = s.URL + "/v1"
+	return c
+}
+
+// a handler decoding the request into a T and replying with reply's result
+func jsonHandler[T any](t *testing.T, reply func(T) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var in T
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reply(in))
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	var query string
+	c := apiStandIn(t, map[string]http.HandlerFunc{
+		"/models": func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.RawQuery
+			w.Write([]byte(`{}`))
+		},
+	})
+	resp, err := c.DoRequest("GET", "models?limit=2", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if query != "limit=2" {
+		t.Errorf("query %q", query)
+	}
+	if _, err := c.DoRequest("GET", "nonesuch", nil, nil); err == nil {
+		t.Error("no error for a 404")
+	}
+}
@@ -0,0 +1,622 @@
+package openai
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// a request to /v1/responses
+type ResponseRequest struct {
+	Model              string            `json:"model"`
+	Input              []ResponseItem    `json:"input"`
+	Instructions       string            `json:"instructions,omitempty"`
+	PreviousResponseID string            `json:"previous_response_id,omitempty"` // continues that response's conversation
+	Tools              []ResponseTool    `json:"tools,omitempty"`
+	ToolChoice         any               `json:"tool_choice,omitempty"` // auto, none, required, or {"type": "function", "name": ...}
+	ParallelToolCalls  *bool             `json:"parallel_tool_calls,omitempty"`
+	Text               *ResponseText     `json:"text,omitempty"`
+	Reasoning          *Reasoning        `json:"reasoning,omitempty"`
+	Include            []string          `json:"include,omitempty"` // e.g. reasoning.encrypted_content
+	MaxOutputTokens    *int              `json:"max_output_tokens,omitempty"`
+	Temperature        *float64          `json:"temperature,omitempty"`
+	TopP               *float64          `json:"top_p,omitempty"`
+	Truncation         string            `json:"truncation,omitempty"` // auto or disabled
+	Store              *bool             `json:"store,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+	User               string            `json:"user,omitempty"`
+	ServiceTier        string            `json:"service_tier,omitempty"`
+	Stream             bool              `json:"stream,omitempty"`
+}
+
+// an input or output item: a message, a function call or its output, a
+// reasoning item, or a hosted tool call, according to Type
+type ResponseItem struct {
+	Type    string            `json:"type"`
+	ID      string            `json:"id,omitempty"`
+	Status  string            `json:"status,omitempty"`
+	Role    string            `json:"role,omitempty"`    // messages
+	Content []ResponseContent `json:"content,omitempty"` // messages
+
+	CallID    string `json:"call_id,omitempty"`   // function_call and function_call_output
+	Name      string `json:"name,omitempty"`      // function_call
+	Arguments string `json:"arguments,omitempty"` // function_call
+	Output    string `json:"output,omitempty"`    // function_call_output
+
+	Summary          []ResponseContent `json:"summary,omitempty"`           // reasoning
+	EncryptedContent string            `json:"encrypted_content,omitempty"` // reasoning
+
+	Action  any      `json:"action,omitempty"`  // web_search_call, computer_call
+	Queries []string `json:"queries,omitempty"` // file_search_call
+	Results any      `json:"results,omitempty"` // file_search_call
+	Code    string   `json:"code,omitempty"`    // code_interpreter_call
+	Result  string   `json:"result,omitempty"`  // image_generation_call, base64
+}
+
+// a part of a message or reasoning item
+type ResponseContent struct {
+	Type        string `json:"type"` // input_text, input_image, input_file, output_text, refusal, or summary_text
+	Text        string `json:"text,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+	FileID      string `json:"file_id,omitempty"`
+	FileData    string `json:"file_data,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+	Refusal     string `json:"refusal,omitempty"`
+	Annotations []any  `json:"annotations,omitempty"`
+}
+
+// a function tool, or a hosted tool like web_search_preview, file_search, or
+// code_interpreter
+type ResponseTool struct {
+	Type           string   `json:"type"`
+	Name           string   `json:"name,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	Parameters     any      `json:"parameters,omitempty"`
+	Strict         *bool    `json:"strict,omitempty"`
+	VectorStoreIDs []string `json:"vector_store_ids,omitempty"` // file_search
+	MaxNumResults  int      `json:"max_num_results,omitempty"`  // file_search
+	Container      any      `json:"container,omitempty"`        // code_interpreter
+	SearchContext  string   `json:"search_context_size,omitempty"`
+}
+
+// a function tool for f, with a non-strict schema
+func FunctionTool(f FunctionI) ResponseTool {
+	fd := functionDefinition(f)
+	return ResponseTool{
+		Type:        "function",
+		Name:        fd.Name,
+		Description: fd.Description,
+		Parameters:  fd.Parameters,
+		Strict:      Ptr(false),
+	}
+}
+
+func WebSearchTool() ResponseTool {
+	return ResponseTool{Type: "web_search_preview"}
+}
+
+func FileSearchTool(vectorStoreIDs ...string) ResponseTool {
+	return ResponseTool{Type: "file_search", VectorStoreIDs: vectorStoreIDs}
+}
+
+func CodeInterpreterTool() ResponseTool {
+	return ResponseTool{Type: "code_interpreter", Container: map[string]string{"type": "auto"}}
+}
+
+type ResponseText struct {
+	Format ResponseTextFormat `json:"format"`
+}
+
+// text, json_object, or json_schema; the schema fields are flattened here,
+// unlike chat completions
+type ResponseTextFormat struct {
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Schema      any    `json:"schema,omitempty"`
+	Strict      bool   `json:"strict,omitempty"`
+}
+
+// for reasoning models
+type Reasoning struct {
+	Effort  string `json:"effort,omitempty"`  // minimal, low, medium, or high
+	Summary string `json:"summary,omitempty"` // auto, concise, or detailed
+}
+
+type ResponseObject struct {
+	ID                 string         `json:"id"`
+	Object             string         `json:"object"`
+	CreatedAt          int64          `json:"created_at"`
+	Status             string         `json:"status"` // completed, failed, in_progress, cancelled, queued, or incomplete
+	Model              string         `json:"model"`
+	Output             []ResponseItem `json:"output"`
+	PreviousResponseID string         `json:"previous_response_id,omitempty"`
+	Usage              *ResponseUsage `json:"usage,omitempty"`
+	Error              *ResponseError `json:"error,omitempty"`
+	IncompleteDetails  *struct {
+		Reason string `json:"reason"` // max_output_tokens or content_filter
+	} `json:"incomplete_details,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type ResponseUsage struct {
+	InputTokens        int `json:"input_tokens"`
+	OutputTokens       int `json:"output_tokens"`
+	TotalTokens        int `json:"total_tokens"`
+	InputTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"input_tokens_details"`
+	OutputTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"output_tokens_details"`
+}
+
+type ResponseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (r ResponseObject) String() string {
+	return toString(r)
+}
+
+// the concatenated output text of all messages
+func (r ResponseObject) OutputText() string {
+	var list []string
+	for _, x := range r.Output {
+		if x.Type != "message" {
+			continue
+		}
+		for _, c := range x.Content {
+			if c.Type == "output_text" {
+				list = append(list, c.Text)
+			}
+		}
+	}
+	return strings.Join(list, "")
+}
+
+// the concatenated refusals, if any
+func (r ResponseObject) Refusal() string {
+	var list []string
+	for _, x := range r.Output {
+		for _, c := range x.Content {
+			if c.Type == "refusal" {
+				list = append(list, c.Refusal)
+			}
+		}
+	}
+	return strings.Join(list, "")
+}
+
+func (r ResponseObject) FunctionCalls() (out []ResponseItem) {
+	for _, x := range r.Output {
+		if x.Type == "function_call" {
+			out = append(out, x)
+		}
+	}
+	return
+}
+
+// a text message item
+func InputText(role, text string) ResponseItem {
+	return ResponseItem{
+		Type:    "message",
+		Role:    role,
+		Content: []ResponseContent{{Type: "input_text", Text: text}},
+	}
+}
+
+func FunctionCallOutput(callID, output string) ResponseItem {
+	return ResponseItem{
+		Type:   "function_call_output",
+		CallID: callID,
+		Output: output,
+	}
+}
+
+func (c *Client) CreateResponse(ctx context.Context, r ResponseRequest) (*ResponseObject, error) {
+	r.Stream = false
+	var out ResponseObject
+	if err := c.PostContext(ctx, "responses", r, &out); err != nil {
+		return nil, err
+	}
+	if out.Error != nil {
+		return nil, out.Error
+	}
+	return &out, nil
+}
+
+func (c *Client) RetrieveResponse(ctx context.Context, id string) (*ResponseObject, error) {
+	var out ResponseObject
+	if err := c.GetContext(ctx, "responses/"+id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) DeleteResponse(ctx context.Context, id string) error {
+	var out deletion
+	if err := c.DeleteContext(ctx, "responses/"+id, &out); err != nil {
+		return err
+	}
+	if !out.Deleted {
+		return fmt.Errorf("response %q not deleted", id)
+	}
+	return nil
+}
+
+// streaming event types
+const (
+	EventResponseCreated        = "response.created"
+	EventResponseInProgress     = "response.in_progress"
+	EventResponseCompleted      = "response.completed"
+	EventResponseIncomplete     = "response.incomplete"
+	EventResponseFailed         = "response.failed"
+	EventOutputItemAdded        = "response.output_item.added"
+	EventOutputItemDone         = "response.output_item.done"
+	EventContentPartAdded       = "response.content_part.added"
+	EventContentPartDone        = "response.content_part.done"
+	EventOutputTextDelta        = "response.output_text.delta"
+	EventOutputTextDone         = "response.output_text.done"
+	EventRefusalDelta           = "response.refusal.delta"
+	EventRefusalDone            = "response.refusal.done"
+	EventFunctionArgumentsDelta = "response.function_call_arguments.delta"
+	EventFunctionArgumentsDone  = "response.function_call_arguments.done"
+	EventReasoningSummaryDelta  = "response.reasoning_summary_text.delta"
+	EventReasoningSummaryDone   = "response.reasoning_summary_text.done"
+	EventError                  = "error"
+)
+
+// one server-sent event of a streamed response; which fields are set depends on Type
+type ResponseEvent struct {
+	Type           string           `json:"type"`
+	SequenceNumber int              `json:"sequence_number"`
+	Response       *ResponseObject  `json:"response,omitempty"` // response.* lifecycle events
+	OutputIndex    int              `json:"output_index"`
+	ContentIndex   int              `json:"content_index"`
+	ItemID         string           `json:"item_id,omitempty"`
+	Item           *ResponseItem    `json:"item,omitempty"`  // output_item events
+	Part           *ResponseContent `json:"part,omitempty"`  // content_part events
+	Delta          string           `json:"delta,omitempty"` // *.delta events
+	Text           string           `json:"text,omitempty"`  // output_text.done and reasoning summary done
+	Refusal        string           `json:"refusal,omitempty"`
+	Arguments      string           `json:"arguments,omitempty"` // function_call_arguments.done
+	Code           string           `json:"code,omitempty"`      // error
+	Message        string           `json:"message,omitempty"`   // error
+}
+
+// streams the response, passing each event to cb, and returns the final response
+func (c *Client) StreamResponse(ctx context.Context, r ResponseRequest, cb func(ResponseEvent) error) (*ResponseObject, error) {
+	r.Stream = true
+	resp, err := c.DoJSONRequestContext(ctx, "POST", "responses", r, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	s := bufio.NewScanner(resp.Body)
+	s.Buffer(nil, 64<<20)
+	var final *ResponseObject
+	for s.Scan() {
+		data, ok := strings.CutPrefix(s.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var e ResponseEvent
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return nil, fmt.Errorf("bad response event: %w", err)
+		}
+		if cb != nil {
+			if err := cb(e); err != nil {
+				return nil, err
+			}
+		}
+		switch e.Type {
+		case EventError:
+			return nil, &ResponseError{Code: e.Code, Message: e.Message}
+		case EventResponseFailed:
+			if e.Response != nil && e.Response.Error != nil {
+				return nil, e.Response.Error
+			}
+			return nil, fmt.Errorf("response failed")
+		case EventResponseCompleted, EventResponseIncomplete:
+			final = e.Response
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if final == nil {
+		return nil, fmt.Errorf("response stream ended without completion")
+	}
+	return final, nil
+}
+
+// sends the request with a strict json_schema text format derived from T, and
+// decodes the output into T
+func ResponseJSON[T any](ctx context.Context, c *Client, r ResponseRequest) (T, error) {
+	var out T
+	format, err := JSONSchemaResponseFormat[T]()
+	if err != nil {
+		return out, err
+	}
+	r.Text = &ResponseText{Format: textFormat(format)}
+	x, err := c.CreateResponse(ctx, r)
+	if err != nil {
+		return out, err
+	}
+	if refusal := x.Refusal(); len(refusal) > 0 {
+		return out, &RefusalError{Refusal: refusal}
+	}
+	if x.Status == "incomplete" {
+		return out, fmt.Errorf("structured output incomplete")
+	}
+	if err := json.Unmarshal([]byte(x.OutputText()), &out); err != nil {
+		return out, fmt.Errorf("can't decode %T: %w", out, err)
+	}
+	return out, nil
+}
+
+func textFormat(f *ResponseFormat) ResponseTextFormat {
+	out := ResponseTextFormat{Type: f.Type}
+	if s := f.JSONSchema; s != nil {
+		out.Name = s.Name
+		out.Description = s.Description
+		out.Schema = s.Schema
+		out.Strict = s.Strict
+	}
+	return out
+}
+
+// runs the response's function calls with the matching tools, returning their
+// outputs as input items for the next request
+func RunFunctionCalls(r *ResponseObject, funcs ...FunctionI) ([]ResponseItem, error) {
+	m := make(map[string]FunctionI)
+	for _, f := range funcs {
+		m[structName(f)] = f
+	}
+	var out []ResponseItem
+	for _, call := range r.FunctionCalls() {
+		f, ok := m[call.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown func: %q", call.Name)
+		}
+		f.Clear()
+		if err := json.Unmarshal([]byte(call.Arguments), f); err != nil {
+			return nil, fmt.Errorf("%w: can't parse arguments of %q --- %s", err, call.Name, call.Arguments)
+		}
+		s, err := f.Run()
+		if err != nil {
+			return nil, fmt.Errorf("can't run %q: %w", call.Name, err)
+		}
+		out = append(out, FunctionCallOutput(call.CallID, s))
+	}
+	return out, nil
+}
+
+// converts chat messages to input items: tool calls and their results become
+// function_call and function_call_output items
+func MessagesToInput(messages []Message) ([]ResponseItem, error) {
+	var out []ResponseItem
+	for _, m := range messages {
+		switch m.Role {
+		case "tool":
+			out = append(out, FunctionCallOutput(m.ToolCallID, m.Content))
+			continue
+		case "assistant":
+			if text := m.Text(); len(text) > 0 {
+				out = append(out, ResponseItem{
+					Type:    "message",
+					Role:    "assistant",
+					Content: []ResponseContent{{Type: "output_text", Text: text}},
+				})
+			}
+			for _, t := range m.ToolCalls {
+				out = append(out, ResponseItem{
+					Type:      "function_call",
+					CallID:    t.ID,
+					Name:      t.FunctionCall.Name,
+					Arguments: t.FunctionCall.Arguments,
+				})
+			}
+			continue
+		}
+		item := ResponseItem{
+			Type: "message",
+			Role: m.Role,
+		}
+		if len(m.Content) > 0 {
+			item.Content = append(item.Content, ResponseContent{Type: "input_text", Text: m.Content})
+		}
+		for _, p := range m.Parts {
+			switch p.Type {
+			case "text":
+				item.Content = append(item.Content, ResponseContent{Type: "input_text", Text: p.Text})
+			case "image_url":
+				if p.ImageURL == nil {
+					return nil, fmt.Errorf("image content without a url")
+				}
+				item.Content = append(item.Content, ResponseContent{
+					Type:     "input_image",
+					ImageURL: p.ImageURL.URL,
+					Detail:   cmp.Or(p.ImageURL.Detail, DetailAuto),
+				})
+			default:
+				return nil, fmt.Errorf("unsupported content for responses: %q", p.Type)
+			}
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+// the responses equivalent of a chat request, with its function tools; an error
+// if it sets parameters responses has no equivalent for
+func ChatToResponseRequest(r ChatRequest) (*ResponseRequest, error) {
+	input, err := MessagesToInput(r.Messages)
+	if err != nil {
+		return nil, err
+	}
+	p := r.parameters()
+	var unsupported []string
+	for _, x := range []struct {
+		name string
+		set  bool
+	}{
+		{"n", p.N != nil && *p.N != 1},
+		{"stop", len(p.Stop) > 0},
+		{"seed", p.Seed != nil},
+		{"presence_penalty", p.PresencePenalty != nil},
+		{"frequency_penalty", p.FrequencyPenalty != nil},
+		{"logit_bias", len(p.LogitBias) > 0},
+		{"logprobs", p.LogProbs},
+		{"top_logprobs", p.TopLogProbs != nil},
+	} {
+		if x.set {
+			unsupported = append(unsupported, x.name)
+		}
+	}
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("responses has no equivalent for %s", strings.Join(unsupported, ", "))
+	}
+	out := ResponseRequest{
+		Model:             r.Model,
+		Input:             input,
+		ParallelToolCalls: p.ParallelToolCalls,
+		Temperature:       p.Temperature,
+		TopP:              p.TopP,
+		Store:             p.Store,
+		Metadata:          p.Metadata,
+		User:              p.User,
+		ServiceTier:       p.ServiceTier,
+	}
+	if n := cmp.Or(p.MaxCompletionTokens, p.MaxTokens); n > 0 {
+		out.MaxOutputTokens = &n
+	}
+	for _, t := range r.Tools {
+		if t.Type != "function" || t.Function == nil {
+			return nil, fmt.Errorf("unsupported tool type for responses: %q", t.Type)
+		}
+		out.Tools = append(out.Tools, ResponseTool{
+			Type:        "function",
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  t.Function.Parameters,
+			Strict:      Ptr(false),
+		})
+	}
+	if t := p.ToolChoice; t != nil {
+		if len(t.Function) > 0 {
+			out.ToolChoice = map[string]string{"type": "function", "name": t.Function}
+		} else {
+			out.ToolChoice = t.Mode
+		}
+	}
+	if f := r.ResponseFormat; f != nil {
+		out.Text = &ResponseText{Format: textFormat(f)}
+	}
+	return &out, nil
+}
+
+// the chat completion equivalent of a response, for code built around chat
+func (r ResponseObject) ChatCompletion() *ChatCompletionResponse {
+	m := Message{
+		Role:    "assistant",
+		Content: r.OutputText(),
+		Refusal: r.Refusal(),
+	}
+	for _, call := range r.FunctionCalls() {
+		m.ToolCalls = append(m.ToolCalls, ToolCall{
+			ID:   call.CallID,
+			Type: "function",
+			FunctionCall: FunctionCall{
+				Name:      call.Name,
+				Arguments: call.Arguments,
+			},
+		})
+	}
+	finish := "stop"
+	switch {
+	case len(m.ToolCalls) > 0:
+		finish = "tool_calls"
+	case r.IncompleteDetails != nil && r.IncompleteDetails.Reason == "max_output_tokens":
+		finish = "length"
+	case r.IncompleteDetails != nil && r.IncompleteDetails.Reason == "content_filter":
+		finish = "content_filter"
+	}
+	out := &ChatCompletionResponse{
+		ID:      r.ID,
+		Object:  "chat.completion",
+		Created: int(r.CreatedAt),
+		Model:   r.Model,
+		Choices: []Choice{{Message: m, FinishReason: finish}},
+	}
+	if u := r.Usage; u != nil {
+		out.Usage = &Usage{
+			PromptTokens:     u.InputTokens,
+			CompletionTokens: u.OutputTokens,
+			TotalTokens:      u.TotalTokens,
+			PromptTokensDetails: &PromptTokensDetails{
+				CachedTokens: u.InputTokensDetails.CachedTokens,
+			},
+			CompletionTokensDetails: &CompletionTokensDetails{
+				ReasoningTokens: u.OutputTokensDetails.ReasoningTokens,
+			},
+		}
+	}
+	return out
+}
+
+// for running ChatWithOptions on the responses api
+type ResponsesOptions struct {
+	Tools     []ResponseTool // hosted tools, added to the function tools
+	Reasoning *Reasoning
+	Include   []string
+	Chain     bool // store each response and chain the next via previous_response_id, sending only new messages
+}
+
+// runs one round of the chat loop as a streamed response; previous is the id of
+// the response to continue, if chaining, and recent the messages since it
+func (o *ResponsesOptions) round(ctx context.Context, c *Client, req ChatRequest, previous string, recent []Message, text func(string)) (*ChatCompletionResponse, string, error) {
+	if o.Chain && len(previous) > 0 {
+		req.Messages = recent
+	} else {
+		previous = ""
+	}
+	r, err := ChatToResponseRequest(req)
+	if err != nil {
+		return nil, "", err
+	}
+	r.PreviousResponseID = previous
+	r.Tools = append(r.Tools, o.Tools...)
+	r.Reasoning = o.Reasoning
+	r.Include = o.Include
+	if o.Chain {
+		r.Store = Ptr(true)
+	}
+	x, err := c.StreamResponse(ctx, *r, func(e ResponseEvent) error {
+		switch e.Type {
+		case EventOutputTextDelta:
+			text(e.Delta)
+		case EventOutputItemAdded:
+			if e.Item != nil && e.Item.Type == "function_call" {
+				fmt.Print(e.Item.Name)
+			}
+		case EventFunctionArgumentsDelta:
+			fmt.Print(e.Delta)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return x.ChatCompletion(), x.ID, nil
+}
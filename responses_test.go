@@ -0,0 +1,70 @@
+package openai
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestChatToResponseRequest(t *testing.T) {
+	r := ChatRequest{
+		Model: "gpt-4o",
+		Messages: []Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Parts: []Content{TextContent("what's this?"), ImageContent("https://example.com/a.png", "")}},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "a", Type: "function", FunctionCall: FunctionCall{Name: "look", Arguments: "{}"}}}},
+			{Role: "tool", ToolCallID: "a", Content: "a cat"},
+		},
+		Tools:          []Tool{{Type: "function", Function: &Function{Name: "look", Description: "looks"}}},
+		ResponseFormat: &ResponseFormat{Type: "json_object"},
+		MaxTokens:      50,
+		ChatParameters: ChatParameters{
+			MaxCompletionTokens: 100,
+			Temperature:         Ptr(0.0),
+			N:                   Ptr(1),
+			ToolChoice:          ToolChoiceFunction("look"),
+			ParallelToolCalls:   Ptr(false),
+			User:                "u",
+		},
+	}
+	x, err := ChatToResponseRequest(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := json.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"model":"gpt-4o","input":[` +
+		`{"type":"message","role":"system","content":[{"type":"input_text","text":"be brief"}]},` +
+		`{"type":"message","role":"user","content":[{"type":"input_text","text":"what's this?"},{"type":"input_image","image_url":"https://example.com/a.png","detail":"auto"}]},` +
+		`{"type":"function_call","call_id":"a","name":"look","arguments":"{}"},` +
+		`{"type":"function_call_output","call_id":"a","output":"a cat"}],` +
+		`"tools":[{"type":"function","name":"look","description":"looks","strict":false}],` +
+		`"tool_choice":{"name":"look","type":"function"},"parallel_tool_calls":false,"text":{"format":{"type":"json_object"}},` +
+		`"max_output_tokens":100,"temperature":0,"user":"u"}`
+	if string(buf) != want {
+		t.Errorf("got  %s\nwant %s", buf, want)
+	}
+
+	for _, tt := range []struct {
+		p    ChatParameters
+		want string // in the error
+	}{
+		{ChatParameters{N: Ptr(2)}, "n"},
+		{ChatParameters{Stop: []string{"\n"}}, "stop"},
+		{ChatParameters{Seed: Ptr(0)}, "seed"},
+		{ChatParameters{PresencePenalty: Ptr(0.5), FrequencyPenalty: Ptr(0.5)}, "presence_penalty, frequency_penalty"},
+		{ChatParameters{LogitBias: map[int]int{1: 1}}, "logit_bias"},
+		{ChatParameters{LogProbs: true, TopLogProbs: Ptr(2)}, "logprobs, top_logprobs"},
+	} {
+		r := ChatRequest{Model: "gpt-4o", Messages: r.Messages[:1], ChatParameters: tt.p}
+		if _, err := ChatToResponseRequest(r); err == nil || !strings.HasSuffix(err.Error(), " "+tt.want) {
+			t.Errorf("%+v: %v", tt.p, err)
+		}
+	}
+	r.Tools = []Tool{{Type: "code_interpreter"}}
+	if _, err := ChatToResponseRequest(r); err == nil {
+		t.Error("converted a code_interpreter tool")
+	}
+}
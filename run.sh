@@ -1,2 +1,2 @@
 #!/bin/bash -e
-go run main/chat_cmd.go
+go run ./main "$@"
@@ -0,0 +1,261 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// a chat saved as a json file, for resuming later. ChatWithOptions updates and
+// saves the session in its options after every round.
+type Session struct {
+	ID             string         `json:"id"`
+	Title          string         `json:"title,omitempty"` // the first message the user typed, by default
+	Created        time.Time      `json:"created"`
+	Updated        time.Time      `json:"updated"`
+	Model          string         `json:"model"`
+	ResponseFormat string         `json:"response_format,omitempty"`
+	Parameters     ChatParameters `json:"parameters"`
+	Tools          []Tool         `json:"tools,omitempty"` // definitions as of the last save
+	Messages       []Message      `json:"messages"`
+	Usage          Usage          `json:"usage"`
+	Cost           float64        `json:"cost"`           // dollars, where the model's price is known
+	Tree           *Conversation  `json:"tree,omitempty"` // every branch explored, if the chat kept a tree
+
+	store   *SessionStore
+	initial int // messages the session started with, which don't make a title
+}
+
+// sessions as json files in a directory
+type SessionStore struct {
+	Dir string
+}
+
+// the store in the user's config directory
+func DefaultSessionStore() (*SessionStore, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return &SessionStore{Dir: filepath.Join(dir, "xoba-openai", "sessions")}, nil
+}
+
+// a new session for the chat, saved to the store
+func (s *SessionStore) New(o ChatOptions) (*Session, error) {
+	now := time.Now()
+	x := &Session{
+		ID:      uuid.NewString(),
+		Created: now,
+		Updated: now,
+		store:   s,
+		initial: len(o.Messages),
+	}
+	x.update(&o, nil, nil)
+	if err := x.Save(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (s *SessionStore) filename(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+// loads a session by id, or by a prefix of the id matching only one session
+func (s *SessionStore) Load(id string) (*Session, error) {
+	if len(id) == 0 || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("bad session id: %q", id)
+	}
+	buf, err := os.ReadFile(s.filename(id))
+	if os.IsNotExist(err) {
+		ids, err := s.ids()
+		if err != nil {
+			return nil, err
+		}
+		var matches []string
+		for _, x := range ids {
+			if strings.HasPrefix(x, id) {
+				matches = append(matches, x)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("no session %q", id)
+		case 1:
+			return s.Load(matches[0])
+		default:
+			return nil, fmt.Errorf("ambiguous session %q: %d matches", id, len(matches))
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	var x Session
+	if err := json.Unmarshal(buf, &x); err != nil {
+		return nil, fmt.Errorf("bad session %s: %w", id, err)
+	}
+	x.store = s
+	x.initial = len(x.Messages)
+	return &x, nil
+}
+
+func (s *SessionStore) ids() ([]string, error) {
+	list, err := os.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range list {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// all sessions, most recently updated first. files that can't be loaded are
+// skipped, and reported in the error alongside the sessions that could be.
+func (s *SessionStore) List() ([]*Session, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+	var out []*Session
+	var bad []error
+	for _, id := range ids {
+		x, err := s.Load(id)
+		if err != nil {
+			bad = append(bad, err)
+			continue
+		}
+		out = append(out, x)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Updated.After(out[j].Updated)
+	})
+	return out, errors.Join(bad...)
+}
+
+// sessions whose title or messages contain the query, ignoring case; bad
+// files are reported as for List
+func (s *SessionStore) Search(query string) ([]*Session, error) {
+	list, err := s.List()
+	query = strings.ToLower(query)
+	var out []*Session
+	for _, x := range list {
+		if x.Contains(query) {
+			out = append(out, x)
+		}
+	}
+	return out, err
+}
+
+func (s *SessionStore) Delete(id string) error {
+	return os.Remove(s.filename(id))
+}
+
+// whether the title or any message contains the lowercase query
+func (x *Session) Contains(query string) bool {
+	if strings.Contains(strings.ToLower(x.Title), query) {
+		return true
+	}
+	for _, m := range x.Messages {
+		if strings.Contains(strings.ToLower(m.Text()), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func (x Session) String() string {
+	return fmt.Sprintf("%s  %s  %-12s %3d messages  $%.4f  %s", x.ID, x.Updated.Format(time.DateTime), x.Model, len(x.Messages), x.Cost, x.Title)
+}
+
+// writes the session to its store, via a temporary file so a crash mid-write
+// leaves the previous save intact
+func (x *Session) Save() error {
+	if x.store == nil {
+		return fmt.Errorf("session %s has no store", x.ID)
+	}
+	if err := os.MkdirAll(x.store.Dir, 0700); err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := x.store.filename(x.ID)
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// restores the session into the options, which supply the functions, since
+// those can't be saved
+func (x *Session) Resume(o *ChatOptions) {
+	o.Messages = append([]Message(nil), x.Messages...)
+	o.Model = x.Model
+	o.ResponseFormat = x.ResponseFormat
+	o.ChatParameters = x.Parameters
+	o.Tree = x.Tree
+	o.Session = x
+}
+
+// copies the chat's state into the session, adding the usage of a round
+func (x *Session) update(o *ChatOptions, tools []Tool, r *ChatCompletionResponse) {
+	x.Updated = time.Now()
+	x.Model = o.Model
+	x.ResponseFormat = o.ResponseFormat
+	x.Parameters = o.ChatParameters
+	if tools != nil {
+		x.Tools = append([]Tool(nil), tools...)
+		name := func(t Tool) string {
+			if t.Function == nil {
+				return ""
+			}
+			return t.Function.Name
+		}
+		sort.SliceStable(x.Tools, func(i, j int) bool {
+			return name(x.Tools[i]) < name(x.Tools[j])
+		})
+	}
+	x.Messages = append([]Message(nil), o.Messages...)
+	x.Tree = o.Tree
+	if len(x.Title) == 0 {
+		for _, m := range x.Messages[min(x.initial, len(x.Messages)):] {
+			if m.Role == "user" {
+				x.Title = title(m.Text())
+				break
+			}
+		}
+	}
+	if r != nil && r.Usage != nil {
+		x.Usage.Add(*r.Usage)
+		catalog := o.Catalog
+		if catalog == nil {
+			catalog = DefaultCatalog()
+		}
+		if m, ok := catalog.Lookup(r.Model); ok {
+			x.Cost += m.Cost(r.Usage.PromptTokens, r.Usage.CachedTokens(), r.Usage.CompletionTokens)
+		}
+	}
+}
+
+// the first line of the text, shortened
+func title(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	if r := []rune(s); len(r) > 60 {
+		s = string(r[:60]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,173 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// the api's input limit for speech, in characters
+const MaxSpeechInput = 4096
+
+type SpeechRequest struct {
+	Model          string   `json:"model"` // tts-1, tts-1-hd, or gpt-4o-mini-tts
+	Input          string   `json:"input"`
+	Voice          string   `json:"voice"`                     // alloy, ash, coral, echo, fable, onyx, nova, sage, shimmer, ...
+	ResponseFormat string   `json:"response_format,omitempty"` // mp3 (default), opus, aac, flac, wav, or pcm
+	Speed          *float64 `json:"speed,omitempty"`           // 0.25 to 4
+	Instructions   string   `json:"instructions,omitempty"`    // tone and style; gpt-4o-mini-tts only
+}
+
+// synthesizes speech, streaming the audio to w. input over the api limit is split
+// at sentence boundaries and the audio concatenated; wav chunks are merged under one
+// header, and flac can't be split.
+func (c *Client) Speech(ctx context.Context, r SpeechRequest, w io.Writer) error {
+	if len(r.Model) == 0 {
+		r.Model = "tts-1"
+	}
+	if len(r.Voice) == 0 {
+		r.Voice = "alloy"
+	}
+	chunks := splitSentences(r.Input, MaxSpeechInput)
+	if len(chunks) == 0 {
+		return fmt.Errorf("no input")
+	}
+	if len(chunks) == 1 {
+		return c.speech(ctx, r, w)
+	}
+	switch r.ResponseFormat {
+	case "flac":
+		return fmt.Errorf("input too long for a single flac file: %d characters", len(r.Input))
+	case "wav":
+		var merged *WAV
+		for _, chunk := range chunks {
+			r.Input = chunk
+			buf := new(bytes.Buffer)
+			if err := c.speech(ctx, r, buf); err != nil {
+				return err
+			}
+			wav, err := ParseWAV(buf.Bytes())
+			if err != nil {
+				return err
+			}
+			if merged == nil {
+				merged = wav
+			} else {
+				merged.Data = append(merged.Data, wav.Data...)
+			}
+		}
+		_, err := w.Write(merged.Bytes())
+		return err
+	default:
+		// mp3, aac (adts), and raw pcm concatenate; opus yields a chained ogg stream
+		for _, chunk := range chunks {
+			r.Input = chunk
+			if err := c.speech(ctx, r, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (c *Client) speech(ctx context.Context, r SpeechRequest, w io.Writer) error {
+	resp, err := c.DoJSONRequestContext(ctx, "POST", "audio/speech", r, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// synthesizes speech into a file, inferring the format from its extension
+func (c *Client) SpeechFile(ctx context.Context, r SpeechRequest, filename string) error {
+	if len(r.ResponseFormat) == 0 {
+		r.ResponseFormat = strings.TrimPrefix(filepath.Ext(filename), ".")
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := c.Speech(ctx, r, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var sentenceRE = regexp.MustCompile(`[.!?…]+["'”’)\]]*\s+|\n\s*\n`)
+
+// packs whole sentences into chunks of at most limit bytes, splitting any
+// overlong sentence at spaces
+func splitSentences(text string, limit int) []string {
+	var sentences []string
+	last := 0
+	for _, m := range sentenceRE.FindAllStringIndex(text, -1) {
+		sentences = append(sentences, text[last:m[1]])
+		last = m[1]
+	}
+	sentences = append(sentences, text[last:])
+
+	var out []string
+	var current string
+	flush := func() {
+		if s := strings.TrimSpace(current); len(s) > 0 {
+			out = append(out, s)
+		}
+		current = ""
+	}
+	for _, s := range sentences {
+		if len(current)+len(s) <= limit {
+			current += s
+			continue
+		}
+		flush()
+		for len(s) > limit {
+			i := strings.LastIndexAny(s[:limit], " \t\n")
+			if i <= 0 {
+				for i = limit; i > 0 && !utf8.RuneStart(s[i]); i-- {
+				}
+			}
+			current = s[:i]
+			flush()
+			s = s[i:]
+		}
+		current = s
+	}
+	flush()
+	return out
+}
+
+// for reading assistant replies aloud into numbered files in a directory
+type SpeechOptions struct {
+	Dir     string
+	Request SpeechRequest // settings for each reply; Input is ignored
+}
+
+// writes the text as speech to the next unused reply file, returning its name
+func (s SpeechOptions) speak(ctx context.Context, c *Client, text string) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return "", err
+	}
+	format := s.Request.ResponseFormat
+	if len(format) == 0 {
+		format = "mp3"
+	}
+	for i := 1; ; i++ {
+		name := filepath.Join(s.Dir, fmt.Sprintf("reply-%03d.%s", i, format))
+		if _, err := os.Stat(name); err == nil {
+			continue
+		}
+		r := s.Request
+		r.Input = text
+		r.ResponseFormat = format
+		return name, c.SpeechFile(ctx, r, name)
+	}
+}
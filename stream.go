@@ -10,15 +10,24 @@ type Response struct {
 	FinishReason string
 }
 
+// streams a single reply to the writer, at temperature 0.7
 func (c *Client) Streaming(model string, messages []Message, stream io.Writer) (*Response, error) {
+	return c.StreamingWithParameters(model, messages, ChatParameters{Temperature: Ptr(0.7)}, stream)
+}
+
+// streams a single reply to the writer, with the given parameters
+func (c *Client) StreamingWithParameters(model string, messages []Message, p ChatParameters, stream io.Writer) (*Response, error) {
+	if err := p.singleChoice(); err != nil {
+		return nil, err
+	}
 	chatRequest := ChatRequest{
 		Stream: true,
 		ResponseFormat: &ResponseFormat{
 			Type: "text",
 		},
-		Model:       model,
-		Messages:    messages,
-		Temperature: 0.7,
+		Model:          model,
+		Messages:       messages,
+		ChatParameters: p,
 	}
 	const endpoint = "chat/completions"
 	var r *ChatCompletionResponse
@@ -0,0 +1,120 @@
+package openai
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/invopop/jsonschema"
+)
+
+// returned when the model declines to produce structured output
+type RefusalError struct {
+	Refusal string
+}
+
+func (e *RefusalError) Error() string {
+	return fmt.Sprintf("model refused: %s", e.Refusal)
+}
+
+// sends the request with a strict json_schema response format derived from T,
+// and decodes the reply into T. T must be a struct (or pointer to one).
+func CompleteJSON[T any](ctx context.Context, c *Client, req ChatRequest) (T, error) {
+	var out T
+	format, err := JSONSchemaResponseFormat[T]()
+	if err != nil {
+		return out, err
+	}
+	req.ResponseFormat = format
+	req.Stream = false
+	req.StreamOptions = nil
+	if err := c.Costs.Check(); err != nil {
+		return out, err
+	}
+	var r ChatCompletionResponse
+	if err := c.PostContext(ctx, "chat/completions", req, &r); err != nil {
+		return out, err
+	}
+	c.Costs.Record("json", cmp.Or(r.Model, req.Model), r.Usage)
+	if len(r.Choices) == 0 {
+		return out, fmt.Errorf("no choices")
+	}
+	choice := r.Choices[0]
+	if m := choice.Message; len(m.Refusal) > 0 {
+		return out, &RefusalError{Refusal: m.Refusal}
+	}
+	if choice.FinishReason == "length" {
+		return out, fmt.Errorf("structured output truncated at max tokens")
+	}
+	if err := json.Unmarshal([]byte(choice.Message.Content), &out); err != nil {
+		return out, fmt.Errorf("can't decode %T: %w", out, err)
+	}
+	return out, nil
+}
+
+// a strict json_schema response format for T
+func JSONSchemaResponseFormat[T any]() (*ResponseFormat, error) {
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("structured output needs a struct, not %v", t)
+	}
+	s := StrictSchema(reflect.New(t).Interface())
+	name := regexp.MustCompile(`[^a-zA-Z0-9_-]`).ReplaceAllString(t.Name(), "_")
+	if len(name) == 0 {
+		name = "response"
+	}
+	return &ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &JSONSchemaFormat{
+			Name:   name,
+			Schema: s,
+			Strict: true,
+		},
+	}, nil
+}
+
+// reflects a json schema for v that satisfies strict mode: every property is
+// required and no additional properties are allowed.
+func StrictSchema(v any) *jsonschema.Schema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	r := new(jsonschema.Reflector)
+	r.ExpandedStruct = len(t.Name()) > 0 // unnamed structs are reflected inline, without a definition to expand
+	s := r.Reflect(v)
+	s.Version = ""
+	s.ID = ""
+	strictify(s, make(map[*jsonschema.Schema]bool))
+	return s
+}
+
+func strictify(s *jsonschema.Schema, seen map[*jsonschema.Schema]bool) {
+	if s == nil || seen[s] {
+		return
+	}
+	seen[s] = true
+	if s.Properties != nil {
+		s.Required = nil
+		for p := s.Properties.Oldest(); p != nil; p = p.Next() {
+			s.Required = append(s.Required, p.Key)
+			strictify(p.Value, seen)
+		}
+		s.AdditionalProperties = jsonschema.FalseSchema
+	}
+	for _, d := range s.Definitions {
+		strictify(d, seen)
+	}
+	for _, list := range [][]*jsonschema.Schema{s.AllOf, s.AnyOf, s.OneOf, s.PrefixItems} {
+		for _, x := range list {
+			strictify(x, seen)
+		}
+	}
+	strictify(s.Items, seen)
+}
@@ -0,0 +1,128 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type testAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type testPerson struct {
+	Name string        `json:"name"`
+	Age  *int          `json:"age,omitempty"`
+	Home testAddress   `json:"home"`
+	Work *testAddress  `json:"work,omitempty"`
+	Past []testAddress `json:"past"`
+}
+
+func TestStrictSchema(t *testing.T) {
+	buf, err := json.Marshal(StrictSchema(new(testPerson)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// omitempty and pointer fields are required too, and nested structs are
+	// shared definitions, strict themselves
+	const want = `{"$defs":{"testAddress":{"properties":{"city":{"type":"string"},"zip":{"type":"string"}},"additionalProperties":false,"type":"object","required":["city","zip"]}},` +
+		`"properties":{"name":{"type":"string"},"age":{"type":"integer"},"home":{"$ref":"#/$defs/testAddress"},"work":{"$ref":"#/$defs/testAddress"},"past":{"items":{"$ref":"#/$defs/testAddress"},"type":"array"}},` +
+		`"additionalProperties":false,"type":"object","required":["name","age","home","work","past"]}`
+	if string(buf) != want {
+		t.Errorf("got  %s\nwant %s", buf, want)
+	}
+	buf, err = json.Marshal(StrictSchema(struct {
+		X int `json:"x,omitempty"`
+	}{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"properties":{"x":{"type":"integer"}},"additionalProperties":false,"type":"object","required":["x"]}`; string(buf) != want {
+		t.Errorf("unnamed: got %s", buf)
+	}
+}
+
+func TestJSONSchemaResponseFormat(t *testing.T) {
+	for _, tt := range []struct {
+		format func() (*ResponseFormat, error)
+		name   string // empty if it should fail
+	}{
+		{JSONSchemaResponseFormat[testPerson], "testPerson"},
+		{JSONSchemaResponseFormat[*testPerson], "testPerson"},
+		{JSONSchemaResponseFormat[**testAddress], "testAddress"},
+		{JSONSchemaResponseFormat[struct{ X int }], "response"},
+		{JSONSchemaResponseFormat[int], ""},
+		{JSONSchemaResponseFormat[[]testPerson], ""},
+		{JSONSchemaResponseFormat[map[string]any], ""},
+	} {
+		f, err := tt.format()
+		if (err == nil) != (len(tt.name) > 0) {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if f.Type != "json_schema" || f.JSONSchema.Name != tt.name || !f.JSONSchema.Strict || f.JSONSchema.Schema == nil {
+			t.Errorf("got %+v, want name %s", f.JSONSchema, tt.name)
+		}
+	}
+}
+
+func TestCompleteJSON(t *testing.T) {
+	var requests []ChatRequest
+	var reply Choice
+	c := apiStandIn(t, map[string]http.HandlerFunc{
+		"/chat/completions": jsonHandler(t, func(r ChatRequest) any {
+			requests = append(requests, r)
+			return ChatCompletionResponse{Model: r.Model, Choices: []Choice{reply}}
+		}),
+	})
+	req := ChatRequest{
+		Model:         "gpt-4o",
+		Messages:      []Message{{Role: "user", Content: "who lives at 1 main st?"}},
+		Stream:        true,
+		StreamOptions: &StreamOptions{IncludeUsage: true},
+	}
+	for _, tt := range []struct {
+		reply   Choice
+		want    *testPerson
+		refusal bool
+	}{
+		{
+			Choice{Message: Message{Role: "assistant", Content: `{"name":"ann","age":null,"home":{"city":"x","zip":""},"work":null,"past":[]}`}, FinishReason: "stop"},
+			&testPerson{Name: "ann", Home: testAddress{City: "x"}, Past: []testAddress{}},
+			false,
+		},
+		{Choice{Message: Message{Role: "assistant", Refusal: "i can't help with that"}, FinishReason: "stop"}, nil, true},
+		{Choice{Message: Message{Role: "assistant", Content: `{"name":"a`}, FinishReason: "length"}, nil, false},
+		{Choice{Message: Message{Role: "assistant", Content: `[]`}, FinishReason: "stop"}, nil, false},
+	} {
+		reply = tt.reply
+		got, err := CompleteJSON[testPerson](context.Background(), c, req)
+		var refusal *RefusalError
+		if errors.As(err, &refusal) != tt.refusal || (tt.refusal && refusal.Refusal != tt.reply.Message.Refusal) {
+			t.Errorf("%+v: %v", tt.reply, err)
+		}
+		if (err == nil) != (tt.want != nil) {
+			t.Errorf("%+v: %v", tt.reply, err)
+		} else if err == nil && !reflect.DeepEqual(got, *tt.want) {
+			t.Errorf("got %+v", got)
+		}
+	}
+	for _, r := range requests {
+		if r.Stream || r.StreamOptions != nil {
+			t.Errorf("sent stream %v, options %+v", r.Stream, r.StreamOptions)
+		}
+		if f := r.ResponseFormat; f == nil || f.JSONSchema == nil || f.JSONSchema.Name != "testPerson" || !f.JSONSchema.Strict {
+			t.Errorf("sent format %+v", f)
+		}
+	}
+	if len(requests) != 4 || !req.Stream {
+		t.Errorf("%d requests; stream %v", len(requests), req.Stream)
+	}
+}
@@ -0,0 +1,335 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// one timed subtitle; text may span several lines
+type Cue struct {
+	Start, End time.Duration
+	Text       string
+}
+
+type Subtitles []Cue
+
+// parses srt or webvtt, detected by the WEBVTT header
+func ParseSubtitles(text string) (Subtitles, error) {
+	if strings.HasPrefix(strings.TrimPrefix(text, "\ufeff"), "WEBVTT") {
+		return ParseVTT(text)
+	}
+	return ParseSRT(text)
+}
+
+func ParseSRT(text string) (Subtitles, error) {
+	return parseCues(text, false)
+}
+
+func ParseVTT(text string) (Subtitles, error) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	if !strings.HasPrefix(text, "WEBVTT") {
+		return nil, fmt.Errorf("missing WEBVTT header")
+	}
+	return parseCues(text, true)
+}
+
+// both formats are blank-line separated blocks with an optional identifier line,
+// a timing line, and then the text
+func parseCues(text string, vtt bool) (Subtitles, error) {
+	var out Subtitles
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	for i, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		if vtt && i == 0 {
+			continue // header
+		}
+		if len(lines) == 0 || len(strings.TrimSpace(lines[0])) == 0 {
+			continue
+		}
+		if vtt {
+			switch strings.Fields(lines[0])[0] {
+			case "NOTE", "STYLE", "REGION":
+				continue
+			}
+		}
+		if !strings.Contains(lines[0], "-->") {
+			lines = lines[1:]
+		}
+		if len(lines) == 0 || !strings.Contains(lines[0], "-->") {
+			return nil, fmt.Errorf("no timing in cue: %q", block)
+		}
+		from, to, _ := strings.Cut(lines[0], "-->")
+		start, err := parseTimestamp(from)
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Fields(to) // vtt may have cue settings after the end time
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("no end time in cue: %q", block)
+		}
+		end, err := parseTimestamp(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Cue{
+			Start: start,
+			End:   end,
+			Text:  strings.Join(lines[1:], "\n"),
+		})
+	}
+	return out, nil
+}
+
+// parses [hh:]mm:ss[,.]ttt
+func parseTimestamp(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	hms, frac, ok := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	if !ok || len(frac) != 3 {
+		return 0, fmt.Errorf("bad timestamp: %q", s)
+	}
+	parts := strings.Split(hms, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("bad timestamp: %q", s)
+	}
+	var d time.Duration
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, fmt.Errorf("bad timestamp: %q", s)
+		}
+		d = d*60 + time.Duration(n)
+	}
+	ms, err := strconv.Atoi(frac)
+	if err != nil {
+		return 0, fmt.Errorf("bad timestamp: %q", s)
+	}
+	return d*time.Second + time.Duration(ms)*time.Millisecond, nil
+}
+
+func formatTimestamp(d time.Duration, sep string) string {
+	d = max(d, 0).Round(time.Millisecond)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, sep, d.Milliseconds()%1000)
+}
+
+func (s Subtitles) SRT() string {
+	w := new(strings.Builder)
+	for i, c := range s {
+		fmt.Fprintf(w, "%d\n%s --> %s\n%s\n\n", i+1, formatTimestamp(c.Start, ","), formatTimestamp(c.End, ","), c.Text)
+	}
+	return w.String()
+}
+
+func (s Subtitles) VTT() string {
+	w := new(strings.Builder)
+	w.WriteString("WEBVTT\n\n")
+	for _, c := range s {
+		fmt.Fprintf(w, "%s --> %s\n%s\n\n", formatTimestamp(c.Start, "."), formatTimestamp(c.End, "."), c.Text)
+	}
+	return w.String()
+}
+
+// formats as srt or vtt
+func (s Subtitles) Format(format string) (string, error) {
+	switch format {
+	case "srt":
+		return s.SRT(), nil
+	case "vtt":
+		return s.VTT(), nil
+	default:
+		return "", fmt.Errorf("unsupported subtitle format: %q", format)
+	}
+}
+
+// all the cue text, space-separated
+func (s Subtitles) Text() string {
+	var list []string
+	for _, c := range s {
+		list = append(list, strings.Join(strings.Fields(c.Text), " "))
+	}
+	return strings.Join(list, " ")
+}
+
+// a copy with all times moved by d, dropping cues that end up entirely before zero
+func (s Subtitles) Shift(d time.Duration) Subtitles {
+	var out Subtitles
+	for _, c := range s {
+		c.Start += d
+		c.End += d
+		if c.End <= 0 {
+			continue
+		}
+		c.Start = max(c.Start, 0)
+		out = append(out, c)
+	}
+	return out
+}
+
+// cues from a verbose_json transcription, preferring segments over words
+func TranscriptionSubtitles(t *Transcription, o SegmentOptions) Subtitles {
+	if len(t.Segments) == 0 && len(t.Words) > 0 {
+		return WordSubtitles(t.Words, o)
+	}
+	var out Subtitles
+	for _, s := range t.Segments {
+		out = append(out, Cue{
+			Start: seconds(s.Start),
+			End:   seconds(s.End),
+			Text:  strings.TrimSpace(s.Text),
+		})
+	}
+	return out.Resegment(o)
+}
+
+func seconds(f float64) time.Duration {
+	return time.Duration(f * float64(time.Second)).Round(time.Millisecond)
+}
+
+type SegmentOptions struct {
+	MaxChars int // per line, defaults to 42
+	MaxLines int // per cue, defaults to 2
+}
+
+func (o *SegmentOptions) defaults() {
+	if o.MaxChars <= 0 {
+		o.MaxChars = 42
+	}
+	if o.MaxLines <= 0 {
+		o.MaxLines = 2
+	}
+}
+
+// greedily packs timed words into cues within the line limits, with exact timing
+func WordSubtitles(words []TranscriptionWord, o SegmentOptions) Subtitles {
+	o.defaults()
+	var out Subtitles
+	var current []TranscriptionWord
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		var list []string
+		for _, w := range current {
+			list = append(list, strings.TrimSpace(w.Word))
+		}
+		out = append(out, Cue{
+			Start: seconds(current[0].Start),
+			End:   seconds(current[len(current)-1].End),
+			Text:  strings.Join(wrapText(strings.Join(list, " "), o.MaxChars), "\n"),
+		})
+		current = nil
+	}
+	for _, w := range words {
+		var list []string
+		for _, x := range append(current, w) {
+			list = append(list, strings.TrimSpace(x.Word))
+		}
+		if len(current) > 0 && len(wrapText(strings.Join(list, " "), o.MaxChars)) > o.MaxLines {
+			flush()
+		}
+		current = append(current, w)
+	}
+	flush()
+	return out
+}
+
+// rewraps each cue's text to the line limits, splitting long cues into several
+// whose durations are proportional to their share of the characters
+func (s Subtitles) Resegment(o SegmentOptions) Subtitles {
+	o.defaults()
+	var out Subtitles
+	for _, c := range s {
+		lines := wrapText(c.Text, o.MaxChars)
+		if len(lines) == 0 {
+			continue
+		}
+		var groups []string
+		for i := 0; i < len(lines); i += o.MaxLines {
+			groups = append(groups, strings.Join(lines[i:min(i+o.MaxLines, len(lines))], "\n"))
+		}
+		var total int
+		for _, g := range groups {
+			total += len(g)
+		}
+		start, done := c.Start, 0
+		for i, g := range groups {
+			done += len(g)
+			end := c.Start + time.Duration(float64(c.End-c.Start)*float64(done)/float64(total)).Round(time.Millisecond)
+			if i == len(groups)-1 {
+				end = c.End
+			}
+			out = append(out, Cue{Start: start, End: end, Text: g})
+			start = end
+		}
+	}
+	return out
+}
+
+// word-wraps text into lines of at most width characters; longer words get their own line
+func wrapText(text string, width int) []string {
+	var lines []string
+	var line string
+	for _, w := range strings.Fields(text) {
+		switch {
+		case len(line) == 0:
+			line = w
+		case len([]rune(line))+1+len([]rune(w)) <= width:
+			line += " " + w
+		default:
+			lines = append(lines, line)
+			line = w
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+type cueTranslation struct {
+	Lines []string `json:"lines"`
+}
+
+// translates cue text via chat completions, keeping the timing. cues are sent in
+// batches, with their line breaks, and the reply must have the same number of cues.
+func (c *Client) TranslateSubtitles(ctx context.Context, s Subtitles, language, model string) (Subtitles, error) {
+	if len(model) == 0 {
+		model = "gpt-4o-mini"
+	}
+	const batch = 50
+	out := make(Subtitles, len(s))
+	copy(out, s)
+	for start := 0; start < len(s); start += batch {
+		end := min(start+batch, len(s))
+		var lines []string
+		for _, x := range s[start:end] {
+			lines = append(lines, x.Text)
+		}
+		r, err := CompleteJSON[cueTranslation](ctx, c, ChatRequest{
+			Model: model,
+			Messages: []Message{
+				{
+					Role: "system",
+					Content: fmt.Sprintf(`translate each subtitle in the json array into %s. reply with exactly one translated line per input line, in the same order, keeping any line breaks within a subtitle. don't merge, split, or skip subtitles.`,
+						language),
+				},
+				{
+					Role:    "user",
+					Content: toString(lines),
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(r.Lines) != len(lines) {
+			return nil, fmt.Errorf("got %d translated cues for %d", len(r.Lines), len(lines))
+		}
+		for i, t := range r.Lines {
+			out[start+i].Text = strings.TrimSpace(t)
+		}
+	}
+	return out, nil
+}
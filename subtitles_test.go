@@ -0,0 +1,192 @@
+package openai
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func ms(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+var testCues = Subtitles{
+	{Start: ms(0), End: ms(2500), Text: "hello there."},
+	{Start: ms(2500), End: ms(3723004), Text: "general kenobi!\nyou are a bold one."},
+}
+
+const testSRT = `1
+00:00:00,000 --> 00:00:02,500
+hello there.
+
+2
+00:00:02,500 --> 01:02:03,004
+general kenobi!
+you are a bold one.
+
+`
+
+const testVTT = `WEBVTT
+
+00:00:00.000 --> 00:00:02.500
+hello there.
+
+00:00:02.500 --> 01:02:03.004
+general kenobi!
+you are a bold one.
+
+`
+
+func TestSubtitlesFormat(t *testing.T) {
+	if got := testCues.SRT(); got != testSRT {
+		t.Errorf("srt:\n%s", got)
+	}
+	if got := testCues.VTT(); got != testVTT {
+		t.Errorf("vtt:\n%s", got)
+	}
+	for _, format := range []string{"srt", "vtt"} {
+		text, err := testCues.Format(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := ParseSubtitles(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(s, testCues) {
+			t.Errorf("%s round trip gave %v", format, s)
+		}
+	}
+	if _, err := testCues.Format("txt"); err == nil {
+		t.Error("formatted as txt")
+	}
+	if got := testCues.Text(); got != "hello there. general kenobi! you are a bold one." {
+		t.Errorf("text %q", got)
+	}
+}
+
+func TestParseSubtitles(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		text string
+		want Subtitles // nil if it shouldn't parse
+	}{
+		{"srt", testSRT, testCues},
+		{"vtt", testVTT, testCues},
+		{"crlf", "1\r\n00:00:01,000 --> 00:00:02,000\r\na\r\n\r\n", Subtitles{{ms(1000), ms(2000), "a"}}},
+		{"no identifier", "00:00:01,000 --> 00:00:02,000\na\n", Subtitles{{ms(1000), ms(2000), "a"}}},
+		{"short timestamps", "WEBVTT\n\n01:02.003 --> 01:04.000\na\n", Subtitles{{ms(62003), ms(64000), "a"}}},
+		{
+			"vtt extras",
+			"\ufeffWEBVTT - title\n\nNOTE a comment\n\nSTYLE\n::cue {}\n\nid\n00:00:01.000 --> 00:00:02.000 align:start\na\n\n\n",
+			Subtitles{{ms(1000), ms(2000), "a"}},
+		},
+		{"empty", "", nil},
+		{"bad timestamp", "1\n00:00:01 --> 00:00:02,000\na\n", nil},
+		{"bad number", "1\n00:0x:01,000 --> 00:00:02,000\na\n", nil},
+		{"no timing", "1\nhello\n", nil},
+		{"no end", "1\n00:00:01,000 -->\na\n", nil},
+	} {
+		s, err := ParseSubtitles(tt.text)
+		switch {
+		case tt.want == nil && tt.text != "" && err == nil:
+			t.Errorf("%s: parsed %v", tt.name, s)
+		case tt.want != nil && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case !reflect.DeepEqual(s, tt.want):
+			t.Errorf("%s: got %v", tt.name, s)
+		}
+	}
+	if _, err := ParseVTT(testSRT); err == nil {
+		t.Error("parsed srt as vtt")
+	}
+}
+
+func TestSubtitlesShift(t *testing.T) {
+	s := Subtitles{{ms(0), ms(1000), "a"}, {ms(1000), ms(3000), "b"}, {ms(4000), ms(5000), "c"}}
+	if got, want := s.Shift(ms(500)), (Subtitles{{ms(500), ms(1500), "a"}, {ms(1500), ms(3500), "b"}, {ms(4500), ms(5500), "c"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v", got)
+	}
+	if got, want := s.Shift(-ms(2000)), (Subtitles{{ms(0), ms(1000), "b"}, {ms(2000), ms(3000), "c"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v", got)
+	}
+	if s[0].Start != 0 {
+		t.Error("shifted the original")
+	}
+}
+
+func TestResegment(t *testing.T) {
+	for _, tt := range []struct {
+		cue  Cue
+		o    SegmentOptions
+		want Subtitles
+	}{
+		{
+			Cue{ms(0), ms(3000), "aaaa bbbb cccc"},
+			SegmentOptions{MaxChars: 9, MaxLines: 1},
+			Subtitles{{ms(0), ms(2077), "aaaa bbbb"}, {ms(2077), ms(3000), "cccc"}},
+		},
+		{
+			Cue{ms(1000), ms(2000), "  aaaa\nbbbb  cccc "},
+			SegmentOptions{MaxChars: 9},
+			Subtitles{{ms(1000), ms(2000), "aaaa bbbb\ncccc"}},
+		},
+		{
+			Cue{ms(0), ms(1000), "a-very-long-word b"},
+			SegmentOptions{MaxChars: 4, MaxLines: 1},
+			Subtitles{{ms(0), ms(941), "a-very-long-word"}, {ms(941), ms(1000), "b"}},
+		},
+		{Cue{ms(0), ms(1000), " \n "}, SegmentOptions{}, nil},
+	} {
+		if got := (Subtitles{tt.cue}).Resegment(tt.o); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q %+v: got %v", tt.cue.Text, tt.o, got)
+		}
+	}
+}
+
+func TestTranscriptionSubtitles(t *testing.T) {
+	words := []TranscriptionWord{
+		{Word: "one", Start: 0, End: 0.4},
+		{Word: " two", Start: 0.5, End: 0.9},
+		{Word: "three", Start: 1.2, End: 1.6},
+		{Word: "four", Start: 1.7, End: 2.0004},
+	}
+	o := SegmentOptions{MaxChars: 8, MaxLines: 1}
+	want := Subtitles{{ms(0), ms(900), "one two"}, {ms(1200), ms(1600), "three"}, {ms(1700), ms(2000), "four"}}
+	if got := TranscriptionSubtitles(&Transcription{Words: words}, o); !reflect.DeepEqual(got, want) {
+		t.Errorf("words: got %v", got)
+	}
+	segments := []TranscriptionSegment{{Start: 0, End: 3, Text: " aaaa bbbb cccc"}}
+	want = Subtitles{{ms(0), ms(2077), "aaaa bbbb"}, {ms(2077), ms(3000), "cccc"}}
+	if got := TranscriptionSubtitles(&Transcription{Segments: segments, Words: words}, SegmentOptions{MaxChars: 9, MaxLines: 1}); !reflect.DeepEqual(got, want) {
+		t.Errorf("segments: got %v", got)
+	}
+}
+
+// chunks of a split file stitch into one file with continuous times
+func TestTranscriptionAppendSubtitles(t *testing.T) {
+	chunk := Subtitles{{ms(0), ms(1000), "a"}, {ms(1000), ms(2500), "b"}}
+	for _, format := range []string{"srt", "vtt"} {
+		text, err := chunk.Format(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out Transcription
+		for _, offset := range []time.Duration{0, 10 * time.Second} {
+			if err := out.append(&Transcription{Text: text}, offset, format); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, _ := append(chunk, chunk.Shift(10*time.Second)...).Format(format)
+		if out.Text != want {
+			t.Errorf("%s: got\n%s", format, out.Text)
+		}
+		if got := out.plainText(format); got != "a b a b" {
+			t.Errorf("%s: plain text %q", format, got)
+		}
+	}
+	var out Transcription
+	if err := out.append(&Transcription{Text: "1\nnonsense\n"}, 0, "srt"); err == nil {
+		t.Error("stitched a bad srt")
+	}
+}
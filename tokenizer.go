@@ -0,0 +1,446 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed encodings
+var encodingFiles embed.FS
+
+// a byte-pair encoding compatible with tiktoken's of the same name
+type Encoding struct {
+	Name    string
+	ranks   map[string]int
+	decoder map[int]string
+	special map[string]int
+	split   func(string) []string
+}
+
+// special tokens, which Encode treats as ordinary text
+var specialTokens = map[string]map[string]int{
+	"cl100k_base": {
+		"<|endoftext|>":   100257,
+		"<|fim_prefix|>":  100258,
+		"<|fim_middle|>":  100259,
+		"<|fim_suffix|>":  100260,
+		"<|endofprompt|>": 100276,
+	},
+	"o200k_base": {
+		"<|endoftext|>":   199999,
+		"<|endofprompt|>": 200018,
+	},
+}
+
+var pretokenizers = map[string]func(string) []string{
+	"cl100k_base": splitCL100K,
+	"o200k_base":  splitO200K,
+}
+
+var (
+	encodingsMu sync.RWMutex
+	encodings   = map[string]func() (*Encoding, error){
+		"cl100k_base": sync.OnceValues(func() (*Encoding, error) { return loadEncoding("cl100k_base") }),
+		"o200k_base":  sync.OnceValues(func() (*Encoding, error) { return loadEncoding("o200k_base") }),
+	}
+)
+
+// the named encoding, cl100k_base or o200k_base, parsed from the embedded
+// rank files on first use, or as set by SetEncoding
+func GetEncoding(name string) (*Encoding, error) {
+	encodingsMu.RLock()
+	f, ok := encodings[name]
+	encodingsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding: %q", name)
+	}
+	return f()
+}
+
+// makes GetEncoding return the encoding, such as one read with ReadEncoding
+// from another copy of a rank file
+func SetEncoding(e *Encoding) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	encodings[e.Name] = func() (*Encoding, error) { return e, nil }
+}
+
+// sha-256 of the published rank files, as tiktoken checks them
+var encodingHashes = map[string]string{
+	"cl100k_base": "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	"o200k_base":  "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+}
+
+func loadEncoding(name string) (*Encoding, error) {
+	buf, err := encodingFiles.ReadFile("encodings/" + name + ".tiktoken")
+	if err != nil {
+		return nil, err
+	}
+	if sum := fmt.Sprintf("%x", sha256.Sum256(buf)); sum != encodingHashes[name] {
+		return nil, fmt.Errorf("encodings/%s.tiktoken has sha-256 %s, want %s", name, sum, encodingHashes[name])
+	}
+	return ReadEncoding(name, bytes.NewReader(buf))
+}
+
+// parses a tiktoken rank file (a base64 token and its rank per line) for one of
+// the known encodings, whose pretokenizer and special tokens it takes
+func ReadEncoding(name string, r io.Reader) (*Encoding, error) {
+	split, ok := pretokenizers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding: %q", name)
+	}
+	e := &Encoding{
+		Name:    name,
+		ranks:   make(map[string]int),
+		decoder: make(map[int]string),
+		special: specialTokens[name],
+		split:   split,
+	}
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		token, rank, ok := strings.Cut(strings.TrimSpace(s.Text()), " ")
+		if !ok {
+			continue
+		}
+		buf, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+		e.ranks[string(buf)] = n
+		e.decoder[n] = string(buf)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(e.ranks) == 0 {
+		return nil, fmt.Errorf("%s: no tokens", name)
+	}
+	for s, n := range e.special {
+		e.decoder[n] = s
+	}
+	return e, nil
+}
+
+// the encoding a model uses: o200k_base for gpt-4o and later and the o-series,
+// cl100k_base for gpt-4, gpt-3.5, and the embedding models
+func EncodingForModel(model string) (*Encoding, error) {
+	name, err := encodingName(model)
+	if err != nil {
+		return nil, err
+	}
+	return GetEncoding(name)
+}
+
+func encodingName(model string) (string, error) {
+	model = strings.TrimPrefix(model, "ft:")
+	switch {
+	case strings.HasPrefix(model, "gpt-4o"),
+		strings.HasPrefix(model, "gpt-4.1"),
+		strings.HasPrefix(model, "gpt-4.5"),
+		strings.HasPrefix(model, "gpt-5"),
+		strings.HasPrefix(model, "gpt-realtime"),
+		strings.HasPrefix(model, "gpt-audio"),
+		strings.HasPrefix(model, "chatgpt-4o"),
+		strings.HasPrefix(model, "o1"),
+		strings.HasPrefix(model, "o3"),
+		strings.HasPrefix(model, "o4"):
+		return "o200k_base", nil
+	case strings.HasPrefix(model, "gpt-4"),
+		strings.HasPrefix(model, "gpt-3.5"),
+		strings.HasPrefix(model, "gpt-35"),
+		strings.HasPrefix(model, "text-embedding-"):
+		return "cl100k_base", nil
+	}
+	return "", fmt.Errorf("no known encoding for model %q", model)
+}
+
+// the tokens of the text, with special tokens encoded as ordinary text
+func (e *Encoding) Encode(text string) []int {
+	var out []int
+	for _, piece := range e.split(text) {
+		out = e.bpe(piece, out)
+	}
+	return out
+}
+
+func (e *Encoding) Count(text string) int {
+	var n int
+	for _, piece := range e.split(text) {
+		if _, ok := e.ranks[piece]; ok {
+			n++
+		} else {
+			n += len(e.bpe(piece, nil))
+		}
+	}
+	return n
+}
+
+// the text of the tokens; a token sequence cut mid-character decodes to
+// invalid utf-8, as with tiktoken's decode_bytes
+func (e *Encoding) Decode(tokens []int) (string, error) {
+	var b strings.Builder
+	for _, t := range tokens {
+		s, ok := e.decoder[t]
+		if !ok {
+			return "", fmt.Errorf("unknown token: %d", t)
+		}
+		b.WriteString(s)
+	}
+	return b.String(), nil
+}
+
+// the rank of a special token like <|endoftext|>
+func (e *Encoding) SpecialToken(name string) (int, bool) {
+	n, ok := e.special[name]
+	return n, ok
+}
+
+// merges the piece's bytes, lowest-ranked pair first, as tiktoken does
+func (e *Encoding) bpe(piece string, out []int) []int {
+	if n, ok := e.ranks[piece]; ok {
+		return append(out, n)
+	}
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+	for len(parts) > 2 {
+		best, at := math.MaxInt, -1
+		for i := 0; i+2 < len(parts); i++ {
+			if n, ok := e.ranks[piece[parts[i]:parts[i+2]]]; ok && n < best {
+				best, at = n, i
+			}
+		}
+		if at < 0 {
+			break
+		}
+		parts = append(parts[:at+1], parts[at+2:]...)
+	}
+	for i := 0; i+1 < len(parts); i++ {
+		out = append(out, e.ranks[piece[parts[i]:parts[i+1]]])
+	}
+	return out
+}
+
+// the pretokenizers below match tiktoken's regular expressions, which use
+// lookahead that go's regexp lacks. cl100k_base's is
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// and o200k_base's is
+//
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// pieces are substrings of the text, so invalid utf-8 passes through as bytes.
+
+func splitCL100K(s string) []string {
+	return splitText(s, cl100kMatch)
+}
+
+func splitO200K(s string) []string {
+	return splitText(s, o200kMatch)
+}
+
+func splitText(s string, match func(t text, i int) int) []string {
+	var out []string
+	t := text(s)
+	for i := 0; i < len(s); {
+		end := match(t, i)
+		out = append(out, s[i:end])
+		i = end
+	}
+	return out
+}
+
+type text string
+
+// the rune at i and its size, or -1 at the end
+func (t text) at(i int) (rune, int) {
+	if i >= len(t) {
+		return -1, 0
+	}
+	return utf8.DecodeRuneInString(string(t[i:]))
+}
+
+// the end of the run of runes from i matching f
+func (t text) span(i int, f func(rune) bool) int {
+	for i < len(t) {
+		r, n := t.at(i)
+		if !f(r) {
+			break
+		}
+		i += n
+	}
+	return i
+}
+
+// the length of a contraction at i, if any
+func (t text) contraction(i int) int {
+	if i >= len(t) || t[i] != '\'' {
+		return 0
+	}
+	for _, c := range []string{"s", "t", "re", "ve", "m", "ll", "d"} {
+		if j := i + 1 + len(c); j <= len(t) && strings.EqualFold(string(t[i+1:j]), c) {
+			return 1 + len(c)
+		}
+	}
+	return 0
+}
+
+func isLetter(r rune) bool  { return unicode.IsLetter(r) }
+func isNumber(r rune) bool  { return unicode.IsNumber(r) }
+func isSpace(r rune) bool   { return unicode.IsSpace(r) }
+func isNewline(r rune) bool { return r == '\r' || r == '\n' }
+
+// [^\r\n\p{L}\p{N}]
+func isPrefix(r rune) bool {
+	return r >= 0 && !isNewline(r) && !isLetter(r) && !isNumber(r)
+}
+
+// [^\s\p{L}\p{N}]
+func isPunct(r rune) bool {
+	return r >= 0 && !isSpace(r) && !isLetter(r) && !isNumber(r)
+}
+
+// [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]
+func isUpper(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// [\p{Ll}\p{Lm}\p{Lo}\p{M}]
+func isLower(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+func cl100kMatch(t text, i int) int {
+	if n := t.contraction(i); n > 0 {
+		return i + n
+	}
+	r, n := t.at(i)
+	if isPrefix(r) {
+		if r1, _ := t.at(i + n); isLetter(r1) {
+			return t.span(i+n, isLetter)
+		}
+	}
+	if isLetter(r) {
+		return t.span(i, isLetter)
+	}
+	if end, ok := t.rest(i, isNewline); ok {
+		return end
+	}
+	return t.whitespace(i)
+}
+
+func o200kMatch(t text, i int) int {
+	r, n := t.at(i)
+	word := func(f func(text, int) int) int {
+		if isPrefix(r) {
+			if end := f(t, i+n); end > 0 {
+				return end
+			}
+		}
+		return f(t, i)
+	}
+	if end := word(o200kLowerWord); end > 0 {
+		return end
+	}
+	if end := word(o200kUpperWord); end > 0 {
+		return end
+	}
+	if end, ok := t.rest(i, func(r rune) bool { return isNewline(r) || r == '/' }); ok {
+		return end
+	}
+	return t.whitespace(i)
+}
+
+// [upper]*[lower]+ with an optional contraction, from i, or 0
+func o200kLowerWord(t text, i int) int {
+	u := t.span(i, isUpper)
+	// backtrack the upper run until a lower run can start
+	for k := u; ; {
+		if r, _ := t.at(k); isLower(r) {
+			end := t.span(k, isLower)
+			return end + t.contraction(end)
+		}
+		if k == i {
+			return 0
+		}
+		_, n := utf8.DecodeLastRuneInString(string(t[:k]))
+		k -= n
+	}
+}
+
+// [upper]+[lower]* with an optional contraction, from i, or 0
+func o200kUpperWord(t text, i int) int {
+	u := t.span(i, isUpper)
+	if u == i {
+		return 0
+	}
+	end := t.span(u, isLower)
+	return end + t.contraction(end)
+}
+
+// \p{N}{1,3} or ` ?[^\s\p{L}\p{N}]+` followed by trailing runes, which the
+// encodings differ on
+func (t text) rest(i int, trailing func(rune) bool) (int, bool) {
+	r, n := t.at(i)
+	if isNumber(r) {
+		end := i
+		for k := 0; k < 3; k++ {
+			r, n := t.at(end)
+			if !isNumber(r) {
+				break
+			}
+			end += n
+		}
+		return end, true
+	}
+	start := i
+	if r == ' ' {
+		if r1, _ := t.at(i + n); isPunct(r1) {
+			start = i + n
+		}
+	}
+	if r1, _ := t.at(start); isPunct(r1) {
+		return t.span(t.span(start, isPunct), trailing), true
+	}
+	return 0, false
+}
+
+// \s*[\r\n]+|\s+(?!\S)|\s+, or a lone rune matching none of the alternatives
+func (t text) whitespace(i int) int {
+	end := t.span(i, isSpace)
+	if end == i {
+		_, n := t.at(i)
+		return i + max(n, 1)
+	}
+	if k := bytes.LastIndexAny([]byte(t[i:end]), "\r\n"); k >= 0 {
+		return i + k + 1
+	}
+	if end == len(t) {
+		return end
+	}
+	// leave the last space to prefix what follows
+	_, n := utf8.DecodeLastRuneInString(string(t[:end]))
+	if end-n > i {
+		return end - n
+	}
+	return end
+}
@@ -0,0 +1,251 @@
+package openai
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// pieces as tiktoken's regular expressions split them
+var pretokenizerTests = []struct {
+	text          string
+	cl100k, o200k []string
+}{
+	{
+		text:   "hello world",
+		cl100k: []string{"hello", " world"},
+		o200k:  []string{"hello", " world"},
+	},
+	{
+		text:   "I'm sure they'll say it's fine",
+		cl100k: []string{"I", "'m", " sure", " they", "'ll", " say", " it", "'s", " fine"},
+		o200k:  []string{"I'm", " sure", " they'll", " say", " it's", " fine"},
+	},
+	{
+		text:   "I'M HERE, YOU'RE",
+		cl100k: []string{"I", "'M", " HERE", ",", " YOU", "'RE"},
+		o200k:  []string{"I'M", " HERE", ",", " YOU'RE"},
+	},
+	{
+		text:   "don't/won't",
+		cl100k: []string{"don", "'t", "/won", "'t"},
+		o200k:  []string{"don't", "/won't"},
+	},
+	{
+		text:   "12345 1234567",
+		cl100k: []string{"123", "45", " ", "123", "456", "7"},
+		o200k:  []string{"123", "45", " ", "123", "456", "7"},
+	},
+	{
+		text:   "año 2024年3月",
+		cl100k: []string{"año", " ", "202", "4", "年", "3", "月"},
+		o200k:  []string{"año", " ", "202", "4", "年", "3", "月"},
+	},
+	{
+		text:   "Ⅻ٣",
+		cl100k: []string{"Ⅻ٣"},
+		o200k:  []string{"Ⅻ٣"},
+	},
+	{
+		text:   "ǅemal",
+		cl100k: []string{"ǅemal"},
+		o200k:  []string{"ǅemal"},
+	},
+	{
+		text:   "😀😀 smile",
+		cl100k: []string{"😀😀", " smile"},
+		o200k:  []string{"😀😀", " smile"},
+	},
+	{
+		text:   "HTMLParser getXMLHttpRequest",
+		cl100k: []string{"HTMLParser", " getXMLHttpRequest"},
+		o200k:  []string{"HTMLParser", " get", "XMLHttp", "Request"},
+	},
+	{
+		text:   "  leading and trailing  ",
+		cl100k: []string{" ", " leading", " and", " trailing", "  "},
+		o200k:  []string{" ", " leading", " and", " trailing", "  "},
+	},
+	{
+		text:   "line one\n\n  line two\r\n",
+		cl100k: []string{"line", " one", "\n\n", " ", " line", " two", "\r\n"},
+		o200k:  []string{"line", " one", "\n\n", " ", " line", " two", "\r\n"},
+	},
+	{
+		text:   "a\t\tb",
+		cl100k: []string{"a", "\t", "\tb"},
+		o200k:  []string{"a", "\t", "\tb"},
+	},
+	{
+		text:   "end.\n\nNext",
+		cl100k: []string{"end", ".\n\n", "Next"},
+		o200k:  []string{"end", ".\n\n", "Next"},
+	},
+	{
+		text:   "x = a/b;//\nok",
+		cl100k: []string{"x", " =", " a", "/b", ";//\n", "ok"},
+		o200k:  []string{"x", " =", " a", "/b", ";//\n", "ok"},
+	},
+	{
+		text:   "path/to/file.go",
+		cl100k: []string{"path", "/to", "/file", ".go"},
+		o200k:  []string{"path", "/to", "/file", ".go"},
+	},
+}
+
+func TestPretokenizers(t *testing.T) {
+	for _, tt := range pretokenizerTests {
+		if got := splitCL100K(tt.text); !slices.Equal(got, tt.cl100k) {
+			t.Errorf("cl100k %q: got %q, want %q", tt.text, got, tt.cl100k)
+		}
+		if got := splitO200K(tt.text); !slices.Equal(got, tt.o200k) {
+			t.Errorf("o200k %q: got %q, want %q", tt.text, got, tt.o200k)
+		}
+	}
+}
+
+// a rank file with the given tokens, ranked in order
+func rankFile(tokens ...string) string {
+	var b strings.Builder
+	for i, x := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(x)), i)
+	}
+	return b.String()
+}
+
+func TestBPE(t *testing.T) {
+	e, err := ReadEncoding("cl100k_base", strings.NewReader(rankFile("a", "b", "c", " ", "ab", "bc", "abc")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		text string
+		want []int
+	}{
+		{"abc", []int{6}},
+		{"abcb", []int{6, 1}}, // ab before bc, then abc
+		{"cab", []int{2, 4}},
+		{"ab abc", []int{4, 3, 6}},
+		{"ccc", []int{2, 2, 2}},
+	} {
+		got := e.Encode(tt.text)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.text, got, tt.want)
+		}
+		if n := e.Count(tt.text); n != len(tt.want) {
+			t.Errorf("%q: counted %d, want %d", tt.text, n, len(tt.want))
+		}
+		if s, err := e.Decode(got); err != nil || s != tt.text {
+			t.Errorf("%q: decoded %q, %v", tt.text, s, err)
+		}
+	}
+	if _, err := e.Decode([]int{99}); err == nil {
+		t.Error("decoded an unknown token")
+	}
+	if n, ok := e.SpecialToken("<|endoftext|>"); !ok || n != 100257 {
+		t.Errorf("endoftext: %d, %v", n, ok)
+	}
+}
+
+func TestReadEncodingErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, file string
+	}{
+		{"p50k_base", rankFile("a")},
+		{"cl100k_base", ""},
+		{"cl100k_base", "!!! 0\n"},
+		{"cl100k_base", "YQ== x\n"},
+	} {
+		if _, err := ReadEncoding(tt.name, strings.NewReader(tt.file)); err == nil {
+			t.Errorf("%s %q: no error", tt.name, tt.file)
+		}
+	}
+}
+
+// token ids from tiktoken itself: its own tests and the openai cookbook
+func TestEncode(t *testing.T) {
+	for _, tt := range []struct {
+		text          string
+		cl100k, o200k []int
+	}{
+		{"hello world", []int{15339, 1917}, []int{24912, 2375}},
+		{" \u00850", []int{220, 126, 227, 15}, []int{220, 126, 227, 15}},
+		{"rer", []int{38149}, []int{27653}},
+		{"'rer", []int{2351, 81}, []int{15770, 259}},
+		{"today\n ", []int{31213, 198, 220}, []int{58744, 198, 220}},
+		{"today\n \n", []int{31213, 27907}, []int{58744, 47812}},
+		{"today\n  \n", []int{31213, 14211}, []int{58744, 31835}},
+		{"tiktoken is great!", []int{83, 1609, 5963, 374, 2294, 0}, []int{83, 8251, 2488, 382, 2212, 0}},
+		{"antidisestablishmentarianism", []int{519, 85342, 34500, 479, 8997, 2191}, []int{493, 129901, 376, 160388, 21203, 2367}},
+		{"2 + 2 = 4", []int{17, 489, 220, 17, 284, 220, 19}, []int{17, 659, 220, 17, 314, 220, 19}},
+		{"お誕生日おめでとう", []int{33334, 45918, 243, 21990, 9080, 33334, 62004, 16556, 78699}, []int{8930, 9697, 243, 128225, 8930, 17693, 4344, 48669}},
+		{"hello world!你好，世界！", []int{15339, 1917, 0, 57668, 53901, 3922, 3574, 244, 98220, 6447}, []int{24912, 2375, 0, 177519, 979, 28428, 3393}},
+		{"<|endoftext|>", []int{27, 91, 8862, 728, 428, 91, 29}, []int{27, 91, 419, 1440, 919, 91, 29}},
+	} {
+		for name, want := range map[string][]int{"cl100k_base": tt.cl100k, "o200k_base": tt.o200k} {
+			e, err := GetEncoding(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := e.Encode(tt.text)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s %q: got %v, want %v", name, tt.text, got, want)
+			}
+			if n := e.Count(tt.text); n != len(want) {
+				t.Errorf("%s %q: counted %d, want %d", name, tt.text, n, len(want))
+			}
+			if s, err := e.Decode(want); err != nil || s != tt.text {
+				t.Errorf("%s %q: decoded %q, %v", name, tt.text, s, err)
+			}
+		}
+	}
+	if _, err := GetEncoding("p50k_base"); err == nil {
+		t.Error("got p50k_base")
+	}
+}
+
+// the cookbook's num_tokens_from_messages example
+func TestCountChatTokens(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "You are a helpful, pattern-following assistant that translates corporate jargon into plain English."},
+		{Role: "system", Name: "example_user", Content: "New synergies will help drive top-line growth."},
+		{Role: "system", Name: "example_assistant", Content: "Things working well together will increase revenue."},
+		{Role: "system", Name: "example_user", Content: "Let's circle back when we have more bandwidth to touch base on opportunities for increased leverage."},
+		{Role: "system", Name: "example_assistant", Content: "Let's talk later when we're less busy about how to do better."},
+		{Role: "user", Content: "This late pivot means we don't have time to boil the ocean for the client deliverable."},
+	}
+	for model, want := range map[string]int{
+		"gpt-3.5-turbo": 129,
+		"gpt-4":         129,
+		"gpt-4o":        124,
+		"gpt-4o-mini":   124,
+	} {
+		if n, err := CountChatTokens(ChatRequest{Model: model, Messages: messages}); err != nil || n != want {
+			t.Errorf("%s: got %d, %v; want %d", model, n, err, want)
+		}
+	}
+	if _, err := CountChatTokens(ChatRequest{Model: "davinci", Messages: messages}); err == nil {
+		t.Error("counted for davinci")
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	for model, want := range map[string]string{
+		"gpt-4o":                           "o200k_base",
+		"gpt-4o-mini-2024-07-18":           "o200k_base",
+		"ft:gpt-4o-mini-2024-07-18:org::x": "o200k_base",
+		"o3-mini":                          "o200k_base",
+		"gpt-4-turbo":                      "cl100k_base",
+		"gpt-3.5-turbo":                    "cl100k_base",
+		"text-embedding-3-small":           "cl100k_base",
+	} {
+		if got, err := encodingName(model); err != nil || got != want {
+			t.Errorf("%s: got %q, %v; want %q", model, got, err, want)
+		}
+	}
+	if _, err := encodingName("davinci"); err == nil {
+		t.Error("davinci has an encoding")
+	}
+}
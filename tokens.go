@@ -0,0 +1,180 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+// prompt tokens for a full chat request: the messages with their formatting
+// overhead, images, and the tool definitions, counted with the model's encoding
+func CountChatTokens(r ChatRequest) (int, error) {
+	e, err := EncodingForModel(r.Model)
+	if err != nil {
+		return 0, err
+	}
+	return e.CountChat(r.Messages, r.Tools), nil
+}
+
+// like CountChatTokens, but estimating for models without a known encoding
+func estimateChatTokens(model string, messages []Message, tools []Tool) int {
+	if e, err := EncodingForModel(model); err == nil {
+		return e.CountChat(messages, tools)
+	}
+	return chatTokens(approxTokens, messages, tools)
+}
+
+// prompt tokens for the messages and tool definitions
+func (e *Encoding) CountChat(messages []Message, tools []Tool) int {
+	return chatTokens(e.Count, messages, tools)
+}
+
+// each message is wrapped in 3 tokens (<|start|>role<|message|>...<|end|>), a
+// name costs one more, and the reply is primed with 3
+func chatTokens(count func(string) int, messages []Message, tools []Tool) int {
+	n := 3
+	for _, m := range messages {
+		n += 3 + count(m.Role)
+		if len(m.Name) > 0 {
+			n += 1 + count(m.Name)
+		}
+		if len(m.Parts) == 0 {
+			n += count(m.Content)
+		}
+		for _, p := range m.Parts {
+			switch p.Type {
+			case "text":
+				n += count(p.Text)
+			case "image_url":
+				if p.ImageURL != nil {
+					n += imageURLTokens(*p.ImageURL)
+				}
+			}
+		}
+		for _, t := range m.ToolCalls {
+			n += 3 + count(t.FunctionCall.Name) + count(t.FunctionCall.Arguments)
+		}
+	}
+	if len(tools) > 0 {
+		n += count(renderTools(tools))
+	}
+	return n
+}
+
+// tokens for an image part, sized from a data url, or as a 1024px square for
+// remote images
+func imageURLTokens(u ImageURL) int {
+	width, height := 1024, 1024
+	if d, err := dataurl.DecodeString(u.URL); err == nil {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(d.Data)); err == nil {
+			width, height = config.Width, config.Height
+		}
+	}
+	return ImageTokens(width, height, u.Detail)
+}
+
+// tool definitions as the model sees them, a typescript namespace
+func renderTools(tools []Tool) string {
+	var b strings.Builder
+	b.WriteString("# Tools\n\n## functions\n\nnamespace functions {\n\n")
+	for _, t := range tools {
+		f := t.Function
+		if f == nil {
+			continue
+		}
+		if len(f.Description) > 0 {
+			fmt.Fprintf(&b, "// %s\n", f.Description)
+		}
+		schema := schemaMap(f.Parameters)
+		if props, _ := schema["properties"].(map[string]any); len(props) > 0 {
+			fmt.Fprintf(&b, "type %s = (_: {\n", f.Name)
+			writeProperties(&b, schema, "")
+			b.WriteString("}) => any;\n\n")
+		} else {
+			fmt.Fprintf(&b, "type %s = () => any;\n\n", f.Name)
+		}
+	}
+	b.WriteString("} // namespace functions")
+	return b.String()
+}
+
+// the schema as generic json, whatever type holds it
+func schemaMap(schema any) map[string]any {
+	buf, err := json.Marshal(schema)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	json.Unmarshal(buf, &m)
+	return m
+}
+
+func writeProperties(b *strings.Builder, schema map[string]any, indent string) {
+	props, _ := schema["properties"].(map[string]any)
+	required := make(map[string]bool)
+	if list, ok := schema["required"].([]any); ok {
+		for _, x := range list {
+			if s, ok := x.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+	var names []string
+	for k := range props {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		p, _ := props[k].(map[string]any)
+		if d, ok := p["description"].(string); ok && len(d) > 0 {
+			fmt.Fprintf(b, "%s// %s\n", indent, d)
+		}
+		optional := "?"
+		if required[k] {
+			optional = ""
+		}
+		fmt.Fprintf(b, "%s%s%s: %s,\n", indent, k, optional, tsType(p, indent))
+	}
+}
+
+func tsType(p map[string]any, indent string) string {
+	if list, ok := p["enum"].([]any); ok && len(list) > 0 {
+		var out []string
+		for _, x := range list {
+			buf, _ := json.Marshal(x)
+			out = append(out, string(buf))
+		}
+		return strings.Join(out, " | ")
+	}
+	t := p["type"]
+	if list, ok := t.([]any); ok && len(list) > 0 {
+		t = list[0]
+	}
+	switch t {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "null":
+		return "null"
+	case "array":
+		items, _ := p["items"].(map[string]any)
+		return tsType(items, indent) + "[]"
+	case "object":
+		if _, ok := p["properties"].(map[string]any); ok {
+			var b strings.Builder
+			b.WriteString("{\n")
+			writeProperties(&b, p, indent+"  ")
+			b.WriteString(indent + "}")
+			return b.String()
+		}
+	}
+	return "any"
+}
@@ -0,0 +1,309 @@
+package openai
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// a conversation as a tree of messages, with named branches whose heads are
+// the latest messages along them. the active branch's path from the root is
+// what's sent to the model, so forking at an earlier message and continuing
+// from there keeps the original path on its own branch.
+type Conversation struct {
+	Nodes    map[string]*MessageNode `json:"nodes"`
+	Branches map[string]string       `json:"branches"` // branch name to head node id
+	Active   string                  `json:"active"`
+	Next     int                     `json:"next"` // for node ids
+}
+
+type MessageNode struct {
+	ID      string  `json:"id"`
+	Parent  string  `json:"parent,omitempty"`
+	Message Message `json:"message"`
+}
+
+// a conversation with the messages on a branch named main
+func NewConversation(messages ...Message) *Conversation {
+	c := &Conversation{
+		Nodes:    make(map[string]*MessageNode),
+		Branches: map[string]string{"main": ""},
+		Active:   "main",
+	}
+	for _, m := range messages {
+		c.Append(m)
+	}
+	return c
+}
+
+// the active branch's head node id, empty for an empty branch
+func (c *Conversation) Head() string {
+	return c.Branches[c.Active]
+}
+
+// adds a message after the active branch's head, returning its node id
+func (c *Conversation) Append(m Message) string {
+	c.Next++
+	id := strconv.Itoa(c.Next)
+	c.Nodes[id] = &MessageNode{ID: id, Parent: c.Head(), Message: m}
+	c.Branches[c.Active] = id
+	return id
+}
+
+// node ids from the root to the given node
+func (c *Conversation) path(id string) []string {
+	var out []string
+	for ; len(id) > 0; id = c.Nodes[id].Parent {
+		out = append(out, id)
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// the active path's nodes, root first
+func (c *Conversation) Path() []*MessageNode {
+	var out []*MessageNode
+	for _, id := range c.path(c.Head()) {
+		out = append(out, c.Nodes[id])
+	}
+	return out
+}
+
+// the active path's messages, for a chat request
+func (c *Conversation) Messages() []Message {
+	var out []Message
+	for _, n := range c.Path() {
+		out = append(out, n.Message)
+	}
+	return out
+}
+
+// branch names, sorted
+func (c *Conversation) BranchNames() []string {
+	var out []string
+	for name := range c.Branches {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// starts a branch whose head is the given node, or the active head if at is
+// empty, and makes it active. a branch can't end between an assistant's tool
+// calls and the replies to them.
+func (c *Conversation) Fork(name, at string) error {
+	if len(at) == 0 {
+		at = c.Head()
+	}
+	if err := c.checkNewBranch(name, at); err != nil {
+		return err
+	}
+	messages := c.messages(c.path(at))
+	if ids := unansweredCalls(messages); len(ids) > 0 {
+		return fmt.Errorf("forking at %s would separate tool calls from their replies: %s", at, strings.Join(ids, ", "))
+	}
+	c.Branches[name] = at
+	c.Active = name
+	return nil
+}
+
+func (c *Conversation) checkNewBranch(name, at string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("no branch name")
+	}
+	if _, ok := c.Branches[name]; ok {
+		return fmt.Errorf("branch %q exists", name)
+	}
+	if _, ok := c.Nodes[at]; len(at) > 0 && !ok {
+		return fmt.Errorf("no node %q", at)
+	}
+	return nil
+}
+
+func (c *Conversation) messages(ids []string) []Message {
+	var out []Message
+	for _, id := range ids {
+		out = append(out, c.Nodes[id].Message)
+	}
+	return out
+}
+
+func (c *Conversation) Switch(name string) error {
+	if _, ok := c.Branches[name]; !ok {
+		return fmt.Errorf("no branch %q", name)
+	}
+	c.Active = name
+	return nil
+}
+
+// replaces a message on the active path with m on a new branch, forked at the
+// message's parent, leaving the original on the old branch. the edited
+// message's later replies to the same tool calls are carried over, so the new
+// branch ends with its tool calls answered.
+func (c *Conversation) Edit(name, id string, m Message) error {
+	path := c.path(c.Head())
+	i := -1
+	for k, x := range path {
+		if x == id {
+			i = k
+			break
+		}
+	}
+	if i < 0 {
+		return fmt.Errorf("node %q isn't on the active branch", id)
+	}
+	if err := c.checkNewBranch(name, id); err != nil {
+		return err
+	}
+	messages := append(c.messages(path[:i]), m)
+	var carried []Message
+	if m.Role == "tool" {
+		for _, x := range path[i+1:] {
+			if r := c.Nodes[x].Message; r.Role == "tool" {
+				carried = append(carried, r)
+				continue
+			}
+			break
+		}
+	}
+	if ids := unansweredCalls(append(messages, carried...)); len(ids) > 0 {
+		return fmt.Errorf("editing %s would leave tool calls unanswered: %s", id, strings.Join(ids, ", "))
+	}
+	c.Branches[name] = c.Nodes[id].Parent
+	c.Active = name
+	c.Append(m)
+	for _, r := range carried {
+		c.Append(r)
+	}
+	return nil
+}
+
+// deletes a branch, other than the active one, along with the messages no
+// other branch leads to
+func (c *Conversation) Prune(name string) error {
+	if _, ok := c.Branches[name]; !ok {
+		return fmt.Errorf("no branch %q", name)
+	}
+	if name == c.Active {
+		return fmt.Errorf("can't prune the active branch")
+	}
+	delete(c.Branches, name)
+	keep := make(map[string]bool)
+	for _, head := range c.Branches {
+		for _, id := range c.path(head) {
+			keep[id] = true
+		}
+	}
+	for id := range c.Nodes {
+		if !keep[id] {
+			delete(c.Nodes, id)
+		}
+	}
+	return nil
+}
+
+// ids of the tool calls in the messages without replies after them
+func unansweredCalls(messages []Message) []string {
+	var out []string
+	for i, m := range messages {
+		for _, t := range m.ToolCalls {
+			answered := false
+			for _, r := range messages[i+1:] {
+				if r.Role == "tool" && r.ToolCallID == t.ID {
+					answered = true
+					break
+				}
+			}
+			if !answered {
+				out = append(out, t.ID)
+			}
+		}
+	}
+	return out
+}
+
+// handles a tree command typed into the chat loop; edit and regenerate fork a
+// branch whose path ends with a user message, for the loop to answer
+func (o *ChatOptions) treeCommand(line string) (regenerate bool, err error) {
+	t := o.Tree
+	fields := strings.Fields(line)
+	arg := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	switch fields[0] {
+	case "/path":
+		for _, n := range t.Path() {
+			text := n.Message.Text()
+			for _, x := range n.Message.ToolCalls {
+				text += fmt.Sprintf(" [%s(%s)]", x.FunctionCall.Name, x.FunctionCall.Arguments)
+			}
+			fmt.Printf("%4s %s: %s\n", n.ID, n.Message.Role, title(text))
+		}
+	case "/branches":
+		for _, name := range t.BranchNames() {
+			mark := " "
+			if name == t.Active {
+				mark = "*"
+			}
+			fmt.Printf("%s %s (at %s)\n", mark, name, t.Branches[name])
+		}
+	case "/fork":
+		err = t.Fork(arg(1), arg(2))
+	case "/switch":
+		err = t.Switch(arg(1))
+	case "/prune":
+		err = t.Prune(arg(1))
+	case "/edit":
+		id := arg(1)
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(line, "/edit")), id))
+		n, ok := t.Nodes[id]
+		switch {
+		case !ok:
+			return false, fmt.Errorf("no node %q", id)
+		case n.Message.Role != "user":
+			return false, fmt.Errorf("only user messages can be edited")
+		case len(text) == 0:
+			return false, fmt.Errorf("usage: /edit <node> <text>")
+		}
+		err = t.Edit(t.newBranchName("edit"), id, Message{Role: "user", Content: text})
+		regenerate = err == nil
+	case "/regenerate":
+		// fork at the last user message, to answer it again
+		path := t.Path()
+		at := ""
+		for i := len(path) - 1; i >= 0; i-- {
+			if path[i].Message.Role == "user" {
+				at = path[i].ID
+				break
+			}
+		}
+		if len(at) == 0 {
+			return false, fmt.Errorf("no user message to regenerate from")
+		}
+		err = t.Fork(t.newBranchName("regen"), at)
+		regenerate = err == nil
+	default:
+		return false, fmt.Errorf("unknown command %s; try /path, /branches, /fork <name> [node], /switch <name>, /prune <name>, /edit <node> <text>, or /regenerate", fields[0])
+	}
+	if err != nil {
+		return false, err
+	}
+	o.Messages = t.Messages()
+	return regenerate, nil
+}
+
+// an unused branch name with the prefix
+func (c *Conversation) newBranchName(prefix string) string {
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s-%d", prefix, i)
+		if _, ok := c.Branches[name]; !ok {
+			return name
+		}
+	}
+}
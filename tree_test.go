@@ -0,0 +1,173 @@
+package openai
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+)
+
+// a conversation with two tool calls answered, then an answer:
+// 1 user, 2 assistant calling a and b, 3 reply to a, 4 reply to b, 5 assistant
+func toolConversation() *Conversation {
+	return NewConversation(
+		Message{Role: "user", Content: "what are 2+2 and 3+3?"},
+		Message{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "a", Type: "function", FunctionCall: FunctionCall{Name: "add", Arguments: `{"x":2,"y":2}`}},
+			{ID: "b", Type: "function", FunctionCall: FunctionCall{Name: "add", Arguments: `{"x":3,"y":3}`}},
+		}},
+		Message{Role: "tool", ToolCallID: "a", Content: "4"},
+		Message{Role: "tool", ToolCallID: "b", Content: "6"},
+		Message{Role: "assistant", Content: "4 and 6"},
+	)
+}
+
+func TestConversationFork(t *testing.T) {
+	for _, tt := range []struct {
+		at string
+		ok bool
+	}{
+		{"1", true},
+		{"2", false}, // at the calls
+		{"3", false}, // with b unanswered
+		{"4", true},
+		{"5", true},
+		{"", true},
+		{"9", false},
+	} {
+		c := toolConversation()
+		err := c.Fork("x", tt.at)
+		if (err == nil) != tt.ok {
+			t.Errorf("forking at %q: %v", tt.at, err)
+			continue
+		}
+		if err != nil {
+			if c.Active != "main" || len(c.Branches) != 1 {
+				t.Errorf("forking at %q: failed, but left branches %v", tt.at, c.Branches)
+			}
+			continue
+		}
+		want := cmp.Or(tt.at, "5")
+		if c.Active != "x" || c.Head() != want || c.Branches["main"] != "5" {
+			t.Errorf("forking at %q: active %s, branches %v", tt.at, c.Active, c.Branches)
+		}
+	}
+	c := toolConversation()
+	for _, name := range []string{"", "main"} {
+		if err := c.Fork(name, "1"); err == nil {
+			t.Errorf("forked a branch named %q", name)
+		}
+	}
+}
+
+func TestConversationEdit(t *testing.T) {
+	c := toolConversation()
+	if err := c.Edit("fix", "3", Message{Role: "tool", ToolCallID: "a", Content: "four"}); err != nil {
+		t.Fatal(err)
+	}
+	// the sibling reply to b is carried over, but not the answer after it
+	if got, want := contents(c.Messages()), "what are 2+2 and 3+3?  four 6"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if ids := unansweredCalls(c.Messages()); len(ids) > 0 {
+		t.Errorf("unanswered %v", ids)
+	}
+	if p := c.Path(); p[1].ID != "2" || p[2].Parent != "2" || p[3].Parent != p[2].ID {
+		t.Errorf("path %v", p)
+	}
+	if err := c.Switch("main"); err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(c.Messages()); got != "what are 2+2 and 3+3?  4 6 4 and 6" {
+		t.Errorf("main is now %q", got)
+	}
+
+	for _, tt := range []struct {
+		name, id string
+		m        Message
+	}{
+		{"x", "9", Message{Role: "user", Content: "hi"}},                                           // not a node
+		{"main", "1", Message{Role: "user", Content: "hi"}},                                        // an existing branch
+		{"x", "2", Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "c", Type: "function"}}}}, // replies aren't carried past calls
+		{"x", "4", Message{Role: "tool", ToolCallID: "c", Content: "6"}},                           // leaving b unanswered
+	} {
+		c := toolConversation()
+		if err := c.Edit(tt.name, tt.id, tt.m); err == nil {
+			t.Errorf("edited %s as %+v", tt.id, tt.m)
+		} else if c.Active != "main" || len(c.Nodes) != 5 {
+			t.Errorf("failed editing %s, but changed the conversation", tt.id)
+		}
+	}
+
+	// nodes off the active path can't be edited
+	c = toolConversation()
+	c.Fork("early", "1")
+	if err := c.Edit("x", "3", Message{Role: "tool", ToolCallID: "a", Content: "four"}); err == nil {
+		t.Error("edited a node off the active path")
+	}
+}
+
+func TestConversationSwitch(t *testing.T) {
+	c := NewConversation(Message{Role: "user", Content: "hi"}, Message{Role: "assistant", Content: "hello"})
+	if err := c.Fork("other", "1"); err != nil {
+		t.Fatal(err)
+	}
+	c.Append(Message{Role: "assistant", Content: "howdy"})
+	for _, tt := range []struct {
+		branch string
+		want   string
+	}{
+		{"main", "hi hello"},
+		{"other", "hi howdy"},
+	} {
+		if err := c.Switch(tt.branch); err != nil {
+			t.Fatal(err)
+		}
+		if got := contents(c.Messages()); got != tt.want {
+			t.Errorf("%s: got %q", tt.branch, got)
+		}
+	}
+	if err := c.Switch("nonesuch"); err == nil || c.Active != "other" {
+		t.Errorf("switched to nonesuch: %v", err)
+	}
+	if got := c.BranchNames(); !slices.Equal(got, []string{"main", "other"}) {
+		t.Errorf("branches %q", got)
+	}
+}
+
+func TestConversationPrune(t *testing.T) {
+	// main: 1 2 3; a: 1 2 4 5; b: 1 2 4 6
+	c := NewConversation(Message{Content: "1"}, Message{Content: "2"}, Message{Content: "3"})
+	c.Fork("a", "2")
+	c.Append(Message{Content: "4"})
+	c.Append(Message{Content: "5"})
+	c.Fork("b", "4")
+	c.Append(Message{Content: "6"})
+	nodes := func() []string {
+		var out []string
+		for id := range c.Nodes {
+			out = append(out, id)
+		}
+		slices.Sort(out)
+		return out
+	}
+	for _, tt := range []struct {
+		branch string
+		ok     bool
+		nodes  []string
+	}{
+		{"b", false, []string{"1", "2", "3", "4", "5", "6"}}, // active
+		{"nonesuch", false, []string{"1", "2", "3", "4", "5", "6"}},
+		{"a", true, []string{"1", "2", "3", "4", "6"}}, // 4 is still on b
+		{"main", true, []string{"1", "2", "4", "6"}},
+	} {
+		if err := c.Prune(tt.branch); (err == nil) != tt.ok {
+			t.Errorf("pruning %s: %v", tt.branch, err)
+		}
+		if got := nodes(); !slices.Equal(got, tt.nodes) {
+			t.Errorf("after pruning %s: %v", tt.branch, got)
+		}
+	}
+	if got := contents(c.Messages()); got != "1 2 4 6" {
+		t.Errorf("b is now %q", got)
+	}
+}
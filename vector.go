@@ -0,0 +1,82 @@
+package openai
+
+import (
+	"container/heap"
+	"math"
+)
+
+// an embedding vector
+type Vector []float32
+
+func Dot(a, b Vector) float64 {
+	var sum float64
+	for i := range min(len(a), len(b)) {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+func (v Vector) Norm() float64 {
+	return math.Sqrt(Dot(v, v))
+}
+
+// a unit-length copy of v; the zero vector is returned unchanged
+func (v Vector) Normalize() Vector {
+	out := make(Vector, len(v))
+	n := v.Norm()
+	if n == 0 {
+		copy(out, v)
+		return out
+	}
+	for i, x := range v {
+		out[i] = float32(float64(x) / n)
+	}
+	return out
+}
+
+// cosine similarity in [-1,1], or 0 if either vector is zero
+func CosineSimilarity(a, b Vector) float64 {
+	na, nb := a.Norm(), b.Norm()
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return Dot(a, b) / (na * nb)
+}
+
+type Match struct {
+	Index int
+	Score float64
+}
+
+// the k candidates most cosine-similar to the query, best first
+func TopK(query Vector, candidates []Vector, k int) []Match {
+	h := new(matchHeap)
+	for i, c := range candidates {
+		m := Match{Index: i, Score: CosineSimilarity(query, c)}
+		if h.Len() < k {
+			heap.Push(h, m)
+		} else if k > 0 && m.Score > (*h)[0].Score {
+			(*h)[0] = m
+			heap.Fix(h, 0)
+		}
+	}
+	out := make([]Match, h.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(h).(Match)
+	}
+	return out
+}
+
+// min-heap on score, for keeping the best k
+type matchHeap []Match
+
+func (h matchHeap) Len() int           { return len(h) }
+func (h matchHeap) Less(i, j int) bool { return h[i].Score < h[j].Score }
+func (h matchHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *matchHeap) Push(x any)        { *h = append(*h, x.(Match)) }
+func (h *matchHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,68 @@
+package openai
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// unit vectors at the given angles, in degrees
+func angles(degrees ...float64) []Vector {
+	var out []Vector
+	for _, d := range degrees {
+		r := d * math.Pi / 180
+		out = append(out, Vector{float32(math.Cos(r)), float32(math.Sin(r))})
+	}
+	return out
+}
+
+func TestTopK(t *testing.T) {
+	candidates := angles(90, 10, 180, 45, 0, 30, 60)
+	for _, tt := range []struct {
+		k    int
+		want []int // indexes, best first
+	}{
+		{1, []int{4}},
+		{3, []int{4, 1, 5}},
+		{7, []int{4, 1, 5, 3, 6, 0, 2}},
+		{20, []int{4, 1, 5, 3, 6, 0, 2}}, // all of them
+		{0, []int{}},
+		{-1, []int{}},
+	} {
+		var got []int
+		for i, m := range TopK(Vector{2, 0}, candidates, tt.k) {
+			got = append(got, m.Index)
+			if want := CosineSimilarity(Vector{1, 0}, candidates[m.Index]); m.Score != want {
+				t.Errorf("k=%d: match %d scored %v, want %v", tt.k, i, m.Score, want)
+			}
+		}
+		if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("k=%d: got %v, want %v", tt.k, got, tt.want)
+		}
+	}
+	if got := TopK(Vector{1, 0}, nil, 3); len(got) != 0 {
+		t.Errorf("no candidates: got %v", got)
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	for _, tt := range []struct {
+		a, b Vector
+		want float64
+	}{
+		{Vector{1, 0}, Vector{5, 0}, 1},
+		{Vector{1, 0}, Vector{0, 3}, 0},
+		{Vector{1, 1}, Vector{-2, -2}, -1},
+		{Vector{0, 0}, Vector{1, 0}, 0},
+	} {
+		if got := CosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%v %v: got %v", tt.a, tt.b, got)
+		}
+	}
+	if n := (Vector{3, 4}).Normalize(); math.Abs(n.Norm()-1) > 1e-7 || math.Abs(float64(n[0])-0.6) > 1e-7 {
+		t.Errorf("normalized %v", n)
+	}
+	if n := (Vector{0, 0}).Normalize(); !reflect.DeepEqual(n, Vector{0, 0}) {
+		t.Errorf("normalized zero %v", n)
+	}
+}
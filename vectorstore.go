@@ -0,0 +1,326 @@
+package openai
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+type Document struct {
+	ID       string            `json:"id"`
+	Text     string            `json:"text"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Vector   Vector            `json:"-"` // embedding; computed by AddTexts if empty
+}
+
+// a pure-go vector store persisted to a single file, supporting exact and
+// approximate (hnsw) cosine similarity search with metadata filters.
+type VectorStore struct {
+	mu       sync.RWMutex
+	filename string
+	docs     map[string]*storedDocument
+	graph    *hnsw
+}
+
+type storedDocument struct {
+	Text     string
+	Metadata map[string]string
+	Node     int
+}
+
+// the on-disk format
+type vectorStoreFile struct {
+	Docs  map[string]*storedDocument
+	Graph *hnsw
+}
+
+type SearchOptions struct {
+	K        int               // number of results, defaults to 5
+	Filter   map[string]string // metadata that results must match exactly
+	Exact    bool              // brute-force search rather than hnsw
+	EfSearch int               // hnsw beam width, defaults to 100
+}
+
+type SearchResult struct {
+	Document
+	Score float64 `json:"score"` // cosine similarity
+}
+
+// opens the store at filename, creating an empty one if it doesn't exist yet
+func OpenVectorStore(filename string) (*VectorStore, error) {
+	s := &VectorStore{
+		filename: filename,
+		docs:     make(map[string]*storedDocument),
+		graph:    newHNSW(),
+	}
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var file vectorStoreFile
+	if err := gob.NewDecoder(f).Decode(&file); err != nil {
+		return nil, fmt.Errorf("can't decode vector store %q: %w", filename, err)
+	}
+	if file.Docs != nil {
+		s.docs = file.Docs
+	}
+	if file.Graph != nil {
+		s.graph = file.Graph
+	}
+	return s, nil
+}
+
+func (s *VectorStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.docs)
+}
+
+func (s *VectorStore) Get(id string) (*Document, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, ok := s.docs[id]
+	if !ok {
+		return nil, false
+	}
+	doc := s.document(id, d)
+	return &doc, true
+}
+
+func (s *VectorStore) document(id string, d *storedDocument) Document {
+	return Document{
+		ID:       id,
+		Text:     d.Text,
+		Metadata: d.Metadata,
+		Vector:   s.graph.Nodes[d.Node].Vector,
+	}
+}
+
+// adds or replaces documents, which must have vectors, then saves the store
+func (s *VectorStore) Add(docs ...Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dims := 0 // the store's, or else the batch's first document's
+	if len(s.graph.Nodes) > 0 {
+		dims = len(s.graph.Nodes[0].Vector)
+	}
+	for _, d := range docs {
+		if len(d.ID) == 0 {
+			return fmt.Errorf("document has no id")
+		}
+		if len(d.Vector) == 0 {
+			return fmt.Errorf("document %q has no vector", d.ID)
+		}
+		if dims == 0 {
+			dims = len(d.Vector)
+		}
+		if len(d.Vector) != dims {
+			return fmt.Errorf("document %q has %d dimensions, store has %d", d.ID, len(d.Vector), dims)
+		}
+	}
+	for _, d := range docs {
+		if old, ok := s.docs[d.ID]; ok {
+			s.graph.Nodes[old.Node].Deleted = true
+		}
+		s.docs[d.ID] = &storedDocument{
+			Text:     d.Text,
+			Metadata: d.Metadata,
+			Node:     s.graph.insert(d.ID, d.Vector.Normalize()),
+		}
+	}
+	return s.save()
+}
+
+// embeds any documents lacking vectors, then adds them
+func (s *VectorStore) AddTexts(ctx context.Context, c *Client, o EmbeddingOptions, docs ...Document) error {
+	var inputs []string
+	var missing []int
+	for i, d := range docs {
+		if len(d.Vector) == 0 {
+			inputs = append(inputs, d.Text)
+			missing = append(missing, i)
+		}
+	}
+	if len(inputs) > 0 {
+		vectors, _, err := c.CreateEmbeddings(ctx, inputs, o)
+		if err != nil {
+			return err
+		}
+		for i, v := range vectors {
+			docs[missing[i]].Vector = v
+		}
+	}
+	return s.Add(docs...)
+}
+
+// deletes documents by id, then saves the store
+func (s *VectorStore) Delete(ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		d, ok := s.docs[id]
+		if !ok {
+			continue
+		}
+		s.graph.Nodes[d.Node].Deleted = true
+		delete(s.docs, id)
+	}
+	// rebuild once tombstones dominate, so search doesn't wade through them
+	if n := len(s.graph.Nodes); n > 100 && s.graph.live() < n/2 {
+		s.rebuild()
+	}
+	return s.save()
+}
+
+func (s *VectorStore) rebuild() {
+	old := s.graph
+	s.graph = newHNSW()
+	ids := make([]string, 0, len(s.docs))
+	for id := range s.docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		d := s.docs[id]
+		d.Node = s.graph.insert(id, old.Nodes[d.Node].Vector)
+	}
+}
+
+// atomically writes the store to its file
+func (s *VectorStore) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.filename), 0755); err != nil {
+		return err
+	}
+	tmp := s.filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(vectorStoreFile{
+		Docs:  s.docs,
+		Graph: s.graph,
+	}); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.filename)
+}
+
+func matches(metadata, filter map[string]string) bool {
+	for k, v := range filter {
+		if metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// the documents most similar to the query, best first
+func (s *VectorStore) Search(query Vector, o SearchOptions) []SearchResult {
+	if o.K == 0 {
+		o.K = 5
+	}
+	if o.EfSearch == 0 {
+		o.EfSearch = 100
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q := query.Normalize()
+	accept := func(node int) bool {
+		d, ok := s.docs[s.graph.Nodes[node].ID]
+		return ok && d.Node == node && matches(d.Metadata, o.Filter)
+	}
+	var found []candidate
+	if !o.Exact {
+		found = s.graph.search(q, o.K, o.EfSearch, accept)
+	}
+	// a selective filter can starve the approximate search, so fall back to exact
+	if len(found) < min(o.K, len(s.docs)) {
+		found = found[:0]
+		for _, d := range s.docs {
+			if matches(d.Metadata, o.Filter) {
+				found = append(found, candidate{node: d.Node, dist: s.graph.dist(q, d.Node)})
+			}
+		}
+		sort.Slice(found, func(i, j int) bool {
+			return found[i].dist < found[j].dist
+		})
+		if len(found) > o.K {
+			found = found[:o.K]
+		}
+	}
+	var out []SearchResult
+	for _, c := range found {
+		id := s.graph.Nodes[c.node].ID
+		out = append(out, SearchResult{
+			Document: s.document(id, s.docs[id]),
+			Score:    1 - c.dist,
+		})
+	}
+	return out
+}
+
+// embeds the query text and searches for it
+func (s *VectorStore) SearchText(ctx context.Context, c *Client, eo EmbeddingOptions, query string, o SearchOptions) ([]SearchResult, error) {
+	v, err := c.CreateEmbedding(ctx, query, eo)
+	if err != nil {
+		return nil, err
+	}
+	return s.Search(v, o), nil
+}
+
+// a retrieval tool over a vector store, for grounding answers in local documents
+type DocumentSearch struct {
+	Query      string
+	MaxResults int
+	Filter     map[string]string
+
+	client  *Client
+	store   *VectorStore
+	options EmbeddingOptions
+}
+
+// the embedding options must match those used to build the store
+func NewDocumentSearch(c *Client, s *VectorStore, o EmbeddingOptions) *DocumentSearch {
+	return &DocumentSearch{
+		client:  c,
+		store:   s,
+		options: o,
+	}
+}
+
+func (DocumentSearch) Description() string {
+	return "searches the user's internal documents for passages semantically related to the query, optionally filtered by exact metadata values. use it to ground answers in those documents."
+}
+
+func (s *DocumentSearch) Clear() {
+	*s = DocumentSearch{
+		client:  s.client,
+		store:   s.store,
+		options: s.options,
+	}
+}
+
+func (s DocumentSearch) Run() (string, error) {
+	if s.store == nil {
+		return "", fmt.Errorf("no vector store; use NewDocumentSearch")
+	}
+	results, err := s.store.SearchText(context.Background(), s.client, s.options, s.Query, SearchOptions{
+		K:      s.MaxResults,
+		Filter: s.Filter,
+	})
+	if err != nil {
+		return "", err
+	}
+	return toString(results), nil
+}
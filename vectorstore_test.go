@@ -0,0 +1,199 @@
+package openai
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func randomVectors(r *rand.Rand, n, dims int) []Vector {
+	var out []Vector
+	for i := 0; i < n; i++ {
+		v := make(Vector, dims)
+		for j := range v {
+			v[j] = float32(r.NormFloat64())
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// the approximate search finds nearly all of the exact nearest neighbors
+func TestHNSWRecall(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	vectors := randomVectors(r, 2000, 32)
+	h := newHNSW()
+	for i, v := range vectors {
+		h.insert(fmt.Sprint(i), v.Normalize())
+	}
+	all := func(int) bool { return true }
+	const k = 10
+	var found, total int
+	for _, q := range randomVectors(r, 50, 32) {
+		want := make(map[int]bool)
+		for _, m := range TopK(q, vectors, k) {
+			want[m.Index] = true
+		}
+		got := h.search(q.Normalize(), k, 100, all)
+		if len(got) != k {
+			t.Fatalf("got %d results, want %d", len(got), k)
+		}
+		for i, c := range got {
+			if i > 0 && c.dist < got[i-1].dist {
+				t.Fatal("results out of order")
+			}
+			if want[c.node] {
+				found++
+			}
+		}
+		total += k
+	}
+	if recall := float64(found) / float64(total); recall < 0.95 {
+		t.Errorf("recall %.3f", recall)
+	}
+	// each vector is its own nearest neighbor
+	for i := 0; i < len(vectors); i += 97 {
+		got := h.search(vectors[i].Normalize(), 1, 100, all)
+		if len(got) != 1 || got[0].node != i {
+			t.Errorf("vector %d found %v", i, got)
+		}
+	}
+}
+
+func TestHNSWSkipsDeleted(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	h := newHNSW()
+	vectors := randomVectors(r, 300, 8)
+	for i, v := range vectors {
+		h.insert(fmt.Sprint(i), v.Normalize())
+	}
+	for i := 0; i < len(vectors); i += 2 {
+		h.Nodes[i].Deleted = true
+	}
+	if n := h.live(); n != 150 {
+		t.Errorf("%d live nodes", n)
+	}
+	for _, c := range h.search(vectors[0].Normalize(), 20, 100, func(int) bool { return true }) {
+		if c.node%2 == 0 {
+			t.Errorf("found deleted node %d", c.node)
+		}
+	}
+	if got := h.search(vectors[0], 5, 100, func(int) bool { return false }); len(got) != 0 {
+		t.Errorf("found rejected nodes %v", got)
+	}
+	if got := newHNSW().search(vectors[0], 5, 100, func(int) bool { return true }); got != nil {
+		t.Errorf("empty graph found %v", got)
+	}
+}
+
+func TestVectorStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "store")
+	s, err := OpenVectorStore(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := []Document{
+		{ID: "x", Text: "east", Vector: Vector{1, 0, 0}, Metadata: map[string]string{"kind": "a"}},
+		{ID: "y", Text: "north", Vector: Vector{0, 1, 0}, Metadata: map[string]string{"kind": "b"}},
+		{ID: "z", Text: "northeast", Vector: Vector{1, 1, 0}, Metadata: map[string]string{"kind": "a"}},
+		{ID: "w", Text: "up", Vector: Vector{0, 0, 1}, Metadata: map[string]string{"kind": "b"}},
+	}
+	// a batch's vectors must agree, even in an empty store
+	if err := s.Add(docs[0], Document{ID: "v", Vector: Vector{1, 0}}); err == nil || s.Len() != 0 {
+		t.Errorf("added mismatched vectors: %v, %d documents", err, s.Len())
+	}
+	if err := s.Add(docs...); err != nil {
+		t.Fatal(err)
+	}
+	ids := func(results []SearchResult) (out []string) {
+		for _, r := range results {
+			out = append(out, r.ID)
+		}
+		return
+	}
+	for _, tt := range []struct {
+		query Vector
+		o     SearchOptions
+		want  []string
+	}{
+		{Vector{2, 0.1, 0}, SearchOptions{K: 2}, []string{"x", "z"}},
+		{Vector{2, 0.1, 0}, SearchOptions{K: 2, Exact: true}, []string{"x", "z"}},
+		{Vector{2, 0.1, 0}, SearchOptions{K: 2, Filter: map[string]string{"kind": "b"}}, []string{"y", "w"}},
+		{Vector{0, 0, 1}, SearchOptions{K: 1}, []string{"w"}},
+		{Vector{0, 0, 1}, SearchOptions{K: 10, Filter: map[string]string{"kind": "c"}}, nil},
+	} {
+		if got := ids(s.Search(tt.query, tt.o)); !slices.Equal(got, tt.want) {
+			t.Errorf("%v %+v: got %v, want %v", tt.query, tt.o, got, tt.want)
+		}
+	}
+
+	// replacing a document moves it
+	if err := s.Add(Document{ID: "x", Text: "down", Vector: Vector{0, 0, -1}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(s.Search(Vector{1, 0, 0}, SearchOptions{K: 1})); !slices.Equal(got, []string{"z"}) {
+		t.Errorf("after replacing: got %v", got)
+	}
+	if err := s.Delete("z", "nonesuch"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Document{ID: "v", Vector: Vector{1, 0}}); err == nil {
+		t.Error("added a vector of the wrong size")
+	}
+	if err := s.Add(Document{ID: "v"}); err == nil {
+		t.Error("added a document without a vector")
+	}
+
+	// reopening gives the same store
+	s, err = OpenVectorStore(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Len(); n != 3 {
+		t.Errorf("%d documents", n)
+	}
+	if d, ok := s.Get("x"); !ok || d.Text != "down" {
+		t.Errorf("got %v, %v", d, ok)
+	}
+	if _, ok := s.Get("z"); ok {
+		t.Error("deleted document is back")
+	}
+	results := s.Search(Vector{0, 0, -1}, SearchOptions{K: 1})
+	if len(results) != 1 || results[0].ID != "x" || results[0].Score < 0.999 {
+		t.Errorf("got %v", results)
+	}
+}
+
+func TestVectorStoreRebuild(t *testing.T) {
+	s, err := OpenVectorStore(filepath.Join(t.TempDir(), "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(3))
+	vectors := randomVectors(r, 200, 8)
+	var docs []Document
+	for i, v := range vectors {
+		docs = append(docs, Document{ID: fmt.Sprint(i), Vector: v})
+	}
+	if err := s.Add(docs...); err != nil {
+		t.Fatal(err)
+	}
+	var gone []string
+	for i := 0; i < 150; i++ {
+		gone = append(gone, fmt.Sprint(i))
+	}
+	if err := s.Delete(gone...); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.graph.Nodes); n != 50 {
+		t.Errorf("%d nodes after rebuilding", n)
+	}
+	for i := 150; i < 200; i += 7 {
+		got := s.Search(vectors[i], SearchOptions{K: 1})
+		if len(got) != 1 || got[0].ID != fmt.Sprint(i) {
+			t.Errorf("vector %d found %v", i, got)
+		}
+	}
+}
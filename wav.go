@@ -0,0 +1,129 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// a parsed riff/wave file: its format chunk and sample data
+type WAV struct {
+	Format []byte // raw "fmt " chunk payload
+	Data   []byte // raw "data" chunk payload
+}
+
+func ParseWAV(buf []byte) (*WAV, error) {
+	if len(buf) < 12 || string(buf[:4]) != "RIFF" || string(buf[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a wav file")
+	}
+	var w WAV
+	for p := 12; p+8 <= len(buf); {
+		id := string(buf[p : p+4])
+		size := int(binary.LittleEndian.Uint32(buf[p+4:]))
+		p += 8
+		end := p + size
+		if end > len(buf) {
+			// streaming encoders sometimes leave the data size unset
+			if id != "data" {
+				return nil, fmt.Errorf("truncated %q chunk", id)
+			}
+			end = len(buf)
+		}
+		switch id {
+		case "fmt ":
+			w.Format = buf[p:end]
+		case "data":
+			w.Data = buf[p:end]
+		}
+		p = end + size%2 // chunks are padded to even sizes
+	}
+	if len(w.Format) < 16 {
+		return nil, fmt.Errorf("no format chunk")
+	}
+	if w.Data == nil {
+		return nil, fmt.Errorf("no data chunk")
+	}
+	return &w, nil
+}
+
+func (w WAV) Channels() int {
+	return int(binary.LittleEndian.Uint16(w.Format[2:]))
+}
+
+func (w WAV) SampleRate() int {
+	return int(binary.LittleEndian.Uint32(w.Format[4:]))
+}
+
+func (w WAV) ByteRate() int {
+	return int(binary.LittleEndian.Uint32(w.Format[8:]))
+}
+
+// bytes per sample frame, across all channels
+func (w WAV) BlockAlign() int {
+	return int(binary.LittleEndian.Uint16(w.Format[12:]))
+}
+
+func (w WAV) BitsPerSample() int {
+	return int(binary.LittleEndian.Uint16(w.Format[14:]))
+}
+
+func (w WAV) Duration() time.Duration {
+	if w.ByteRate() == 0 {
+		return 0
+	}
+	return time.Duration(float64(len(w.Data)) / float64(w.ByteRate()) * float64(time.Second))
+}
+
+// serializes as a canonical wav file
+func (w WAV) Bytes() []byte {
+	b := new(bytes.Buffer)
+	chunk := func(id string, payload []byte) {
+		b.WriteString(id)
+		binary.Write(b, binary.LittleEndian, uint32(len(payload)))
+		b.Write(payload)
+		if len(payload)%2 == 1 {
+			b.WriteByte(0)
+		}
+	}
+	b.WriteString("RIFF")
+	binary.Write(b, binary.LittleEndian, uint32(0)) // patched below
+	b.WriteString("WAVE")
+	chunk("fmt ", w.Format)
+	chunk("data", w.Data)
+	out := b.Bytes()
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+// a piece of a split wav file, with its start time in the original
+type WAVChunk struct {
+	WAV
+	Offset time.Duration
+}
+
+// splits into consecutive chunks, each of which serializes to at most maxBytes
+func (w WAV) Split(maxBytes int) ([]WAVChunk, error) {
+	const overhead = 64
+	align := max(1, w.BlockAlign())
+	per := (maxBytes - len(w.Format) - overhead) / align * align
+	if per <= 0 {
+		return nil, fmt.Errorf("max chunk size %d too small", maxBytes)
+	}
+	var out []WAVChunk
+	for start := 0; start < len(w.Data); start += per {
+		end := min(start+per, len(w.Data))
+		var offset time.Duration
+		if r := w.ByteRate(); r > 0 {
+			offset = time.Duration(start) * time.Second / time.Duration(r)
+		}
+		out = append(out, WAVChunk{
+			WAV: WAV{
+				Format: w.Format,
+				Data:   w.Data[start:end],
+			},
+			Offset: offset,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,234 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// a pcm format chunk
+func pcmFormat(channels, rate, bits int) []byte {
+	b := make([]byte, 16)
+	align := channels * bits / 8
+	binary.LittleEndian.PutUint16(b[0:], 1)
+	binary.LittleEndian.PutUint16(b[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(b[4:], uint32(rate))
+	binary.LittleEndian.PutUint32(b[8:], uint32(rate*align))
+	binary.LittleEndian.PutUint16(b[12:], uint16(align))
+	binary.LittleEndian.PutUint16(b[14:], uint16(bits))
+	return b
+}
+
+// a riff file of the given chunks, each an id followed by its payload
+func riff(chunks ...[]byte) []byte {
+	b := new(bytes.Buffer)
+	b.WriteString("RIFF")
+	binary.Write(b, binary.LittleEndian, uint32(0))
+	b.WriteString("WAVE")
+	for _, c := range chunks {
+		b.Write(c[:4])
+		binary.Write(b, binary.LittleEndian, uint32(len(c)-4))
+		b.Write(c[4:])
+		if len(c)%2 == 1 {
+			b.WriteByte(0)
+		}
+	}
+	out := b.Bytes()
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+func chunk(id string, payload []byte) []byte {
+	return append([]byte(id), payload...)
+}
+
+func TestParseWAV(t *testing.T) {
+	format := pcmFormat(2, 8000, 16)
+	data := []byte("0123456789abcdef")
+	unsized := riff(chunk("fmt ", format), chunk("data", data))
+	binary.LittleEndian.PutUint32(unsized[len(unsized)-len(data)-4:], 0xffffffff)
+	for _, tt := range []struct {
+		name string
+		buf  []byte
+		want *WAV // nil if it shouldn't parse
+	}{
+		{"canonical", WAV{Format: format, Data: data}.Bytes(), &WAV{Format: format, Data: data}},
+		{"extra chunks", riff(chunk("LIST", []byte("odd")), chunk("fmt ", format), chunk("fact", []byte{1, 2, 3, 4}), chunk("data", data)), &WAV{Format: format, Data: data}},
+		{"odd data", riff(chunk("fmt ", format), chunk("data", data[:3])), &WAV{Format: format, Data: data[:3]}},
+		{"unsized data", unsized, &WAV{Format: format, Data: data}},
+		{"not riff", []byte("ID3 not a wav file at all"), nil},
+		{"short", []byte("RIFF"), nil},
+		{"no format", riff(chunk("data", data)), nil},
+		{"short format", riff(chunk("fmt ", format[:8]), chunk("data", data)), nil},
+		{"no data", riff(chunk("fmt ", format)), nil},
+		{"truncated chunk", riff(chunk("fmt ", format), chunk("data", data))[:30], nil},
+	} {
+		w, err := ParseWAV(tt.buf)
+		switch {
+		case tt.want == nil && err == nil:
+			t.Errorf("%s: parsed", tt.name)
+		case tt.want != nil && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.want != nil && !reflect.DeepEqual(w, tt.want):
+			t.Errorf("%s: got %v", tt.name, w)
+		}
+	}
+}
+
+func TestWAVFormat(t *testing.T) {
+	w := WAV{Format: pcmFormat(2, 16000, 16), Data: make([]byte, 96000)}
+	if w.Channels() != 2 || w.SampleRate() != 16000 || w.ByteRate() != 64000 || w.BlockAlign() != 4 || w.BitsPerSample() != 16 {
+		t.Errorf("format %v", w.Format)
+	}
+	if d := w.Duration(); d != 1500*time.Millisecond {
+		t.Errorf("duration %v", d)
+	}
+	if d := (WAV{Format: make([]byte, 16), Data: w.Data}).Duration(); d != 0 {
+		t.Errorf("duration %v without a byte rate", d)
+	}
+}
+
+func TestWAVSplit(t *testing.T) {
+	w := WAV{Format: pcmFormat(2, 8000, 16), Data: make([]byte, 10002)}
+	for i := range w.Data {
+		w.Data[i] = byte(i)
+	}
+	for _, max := range []int{200, 1001, 4096, 10002, 1 << 20} {
+		chunks, err := w.Split(max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data []byte
+		for i, c := range chunks {
+			buf := c.Bytes()
+			if len(buf) > max {
+				t.Errorf("%d: chunk %d is %d bytes", max, i, len(buf))
+			}
+			if i < len(chunks)-1 && len(c.Data)%w.BlockAlign() != 0 {
+				t.Errorf("%d: chunk %d splits a sample frame", max, i)
+			}
+			if want := time.Duration(len(data)) * time.Second / time.Duration(w.ByteRate()); c.Offset != want {
+				t.Errorf("%d: chunk %d at %v, want %v", max, i, c.Offset, want)
+			}
+			p, err := ParseWAV(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(p.Format, w.Format) {
+				t.Errorf("%d: chunk %d format %v", max, i, p.Format)
+			}
+			data = append(data, p.Data...)
+		}
+		if !bytes.Equal(data, w.Data) {
+			t.Errorf("%d: the chunks don't add up to the original", max)
+		}
+		if max >= len(w.Bytes()) && len(chunks) != 1 {
+			t.Errorf("%d: %d chunks for a file that fits", max, len(chunks))
+		}
+	}
+	if _, err := w.Split(64); err == nil {
+		t.Error("split into chunks without room for data")
+	}
+}
+
+func TestTranscriptionAppend(t *testing.T) {
+	chunks := []*Transcription{
+		{
+			Task: "transcribe", Language: "english", Duration: 10, Text: " hello there. ",
+			Segments: []TranscriptionSegment{{ID: 0, Start: 0, End: 9.5, Text: " hello there."}},
+			Words:    []TranscriptionWord{{Word: "hello", Start: 0, End: 1}, {Word: "there", Start: 1, End: 2}},
+			Usage:    &TranscriptionUsage{Type: "tokens", InputTokens: 10, InputTokenDetails: &TranscriptionTokenDetails{AudioTokens: 10}, OutputTokens: 3, TotalTokens: 13},
+		},
+		{
+			Task: "transcribe", Language: "english", Duration: 4, Text: "general kenobi.",
+			Segments: []TranscriptionSegment{{ID: 0, Start: 0.5, End: 3, Text: " general kenobi."}},
+			Words:    []TranscriptionWord{{Word: "general", Start: 0.5, End: 1}},
+			Usage:    &TranscriptionUsage{Type: "tokens", InputTokens: 6, InputTokenDetails: &TranscriptionTokenDetails{TextTokens: 1, AudioTokens: 5}, OutputTokens: 2, TotalTokens: 8},
+		},
+	}
+	offsets := []time.Duration{0, 10 * time.Second}
+	var got Transcription
+	for i, c := range chunks {
+		if err := got.append(c, offsets[i], "verbose_json"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := Transcription{
+		Task: "transcribe", Language: "english", Duration: 14, Text: "hello there. general kenobi.",
+		Segments: []TranscriptionSegment{
+			{ID: 0, Start: 0, End: 9.5, Text: " hello there."},
+			{ID: 1, Start: 10.5, End: 13, Text: " general kenobi."},
+		},
+		Words: []TranscriptionWord{
+			{Word: "hello", Start: 0, End: 1},
+			{Word: "there", Start: 1, End: 2},
+			{Word: "general", Start: 10.5, End: 11},
+		},
+		Usage: &TranscriptionUsage{Type: "tokens", InputTokens: 16, InputTokenDetails: &TranscriptionTokenDetails{TextTokens: 1, AudioTokens: 15}, OutputTokens: 5, TotalTokens: 21},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	var text Transcription
+	for _, s := range []string{"one two\n", "", " three\n"} {
+		if err := text.append(&Transcription{Text: s}, time.Minute, "text"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if text.Text != "one two three" {
+		t.Errorf("text %q", text.Text)
+	}
+}
+
+func TestTranscriptionUsage(t *testing.T) {
+	for _, tt := range []struct {
+		chunks []string
+		want   TranscriptionUsage
+	}{
+		{
+			[]string{`{"type":"duration","seconds":9}`, `{"type":"duration","seconds":4}`},
+			TranscriptionUsage{Type: "duration", Seconds: 13},
+		},
+		{
+			[]string{
+				`{"type":"tokens","input_tokens":14,"input_token_details":{"text_tokens":0,"audio_tokens":14},"output_tokens":45,"total_tokens":59}`,
+				`{"type":"tokens","input_tokens":6,"output_tokens":5,"total_tokens":11}`,
+			},
+			TranscriptionUsage{Type: "tokens", InputTokens: 20, InputTokenDetails: &TranscriptionTokenDetails{AudioTokens: 14}, OutputTokens: 50, TotalTokens: 70},
+		},
+	} {
+		var got Transcription
+		for i, c := range tt.chunks {
+			var x Transcription
+			if err := json.Unmarshal([]byte(`{"text":"a","usage":`+c+`}`), &x); err != nil {
+				t.Fatal(err)
+			}
+			if err := got.append(&x, time.Duration(i)*time.Minute, "json"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got.Usage == nil || !reflect.DeepEqual(*got.Usage, tt.want) {
+			t.Errorf("%s: got %+v", tt.chunks, got.Usage)
+		}
+	}
+}
+
+func TestLastWords(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"  a b\n c d ", 2, "c d"},
+		{"a b", 5, "a b"},
+		{"", 3, ""},
+	} {
+		if got := lastWords(tt.s, tt.n); got != tt.want {
+			t.Errorf("%q, %d: got %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}